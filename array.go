@@ -0,0 +1,75 @@
+package firebase
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+)
+
+// maxArrayGaps is the number of missing indexes DecodeArray accepts in an
+// object. Firebase returns sparse lists as objects, but a few items with
+// huge indexes are more likely a map than a list.
+const maxArrayGaps = 1000
+
+// DecodeArray decodes a list read from Firebase, such as the value of a
+// reference, into out, which must be a pointer to a slice.
+//
+// Firebase stores arrays as objects keyed by indexes, and returns them as
+// JSON arrays only when most indexes are used, so v may be a []interface{} or
+// a map[string]interface{} with integer keys. Both are decoded the same way,
+// with the missing indexes left to the zero value. A nil v gives an empty
+// slice. As the slice grows to the largest index, objects missing more than
+// maxArrayGaps indexes are rejected rather than allocating a huge slice.
+func DecodeArray(v interface{}, out interface{}) error {
+	rv := reflect.ValueOf(out)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Slice {
+		return fmt.Errorf("firebase: decode array: %T is not a pointer to a slice", out)
+	}
+
+	items := []interface{}{}
+
+	switch v := v.(type) {
+	case nil:
+	case []interface{}:
+		items = v
+	case map[string]interface{}:
+		indexes := make(map[int]interface{}, len(v))
+		size := 0
+		for k, item := range v {
+			i, err := strconv.Atoi(k)
+			if err != nil || i < 0 {
+				return fmt.Errorf("firebase: decode array: %q is not an index", k)
+			}
+			if i >= len(v)+maxArrayGaps {
+				return fmt.Errorf("firebase: decode array: index %d is too sparse for %d items", i, len(v))
+			}
+
+			indexes[i] = item
+			if i >= size {
+				size = i + 1
+			}
+		}
+
+		items = make([]interface{}, size)
+		for i, item := range indexes {
+			items[i] = item
+		}
+	default:
+		return fmt.Errorf("firebase: decode array: unexpected %T", v)
+	}
+
+	b, err := json.Marshal(items)
+	if err != nil {
+		return fmt.Errorf("firebase: decode array: %w", err)
+	}
+
+	// start from an empty slice so that gaps get the zero value
+	rv.Elem().Set(reflect.MakeSlice(rv.Elem().Type(), 0, len(items)))
+
+	if err := json.Unmarshal(b, out); err != nil {
+		return fmt.Errorf("firebase: decode array: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,48 @@
+package firebase
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestDecodeArray(t *testing.T) {
+	tests := []struct {
+		data     string
+		expected []Name
+	}{
+		{`[{"First":"a"},null,{"First":"c"}]`, []Name{{First: "a"}, {}, {First: "c"}}},
+		{`{"0":{"First":"a"},"2":{"First":"c"}}`, []Name{{First: "a"}, {}, {First: "c"}}},
+		{`null`, []Name{}},
+	}
+
+	for _, test := range tests {
+		var v interface{}
+		json.Unmarshal([]byte(test.data), &v)
+
+		var names []Name
+		if err := DecodeArray(v, &names); err != nil {
+			t.Fatalf("%v\n", err)
+		}
+
+		if !reflect.DeepEqual(names, test.expected) {
+			t.Errorf("Expected %v for %s, got %v\n", test.expected, test.data, names)
+		}
+	}
+}
+
+func TestDecodeArrayErrors(t *testing.T) {
+	var names []Name
+
+	if err := DecodeArray(map[string]interface{}{"a": 1}, &names); err == nil {
+		t.Errorf("Expected an error for a non-numeric key\n")
+	}
+
+	if err := DecodeArray(map[string]interface{}{"50000000": 1}, &names); err == nil {
+		t.Errorf("Expected an error for a sparse index\n")
+	}
+
+	if err := DecodeArray([]interface{}{}, names); err == nil {
+		t.Errorf("Expected an error for a non-pointer\n")
+	}
+}
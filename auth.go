@@ -0,0 +1,300 @@
+package firebase
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// scopes are the OAuth2 scopes needed to access the Realtime Database.
+var scopes = []string{
+	"https://www.googleapis.com/auth/firebase.database",
+	"https://www.googleapis.com/auth/userinfo.email",
+}
+
+// defaultTokenUri is used when the service account key does not specify one.
+const defaultTokenUri = "https://oauth2.googleapis.com/token"
+
+// tokenRefreshMargin is how long before expiry access tokens are refreshed.
+const tokenRefreshMargin = time.Minute
+
+// ServiceAccount mints OAuth2 access tokens from a service account key.
+// Tokens are cached and refreshed shortly before they expire.
+// It is safe for concurrent use.
+type ServiceAccount struct {
+	// HTTPClient is the HTTP client used to obtain tokens.
+	// When nil, http.DefaultClient is used.
+	HTTPClient *http.Client
+
+	email    string
+	key      *rsa.PrivateKey
+	tokenUri string
+
+	mu     sync.Mutex
+	token  string
+	expiry time.Time
+}
+
+// NewServiceAccount returns a ServiceAccount for the given JSON key, as
+// downloaded from the Firebase or Google Cloud console.
+func NewServiceAccount(jsonKey []byte) (*ServiceAccount, error) {
+	var k struct {
+		ClientEmail string `json:"client_email"`
+		PrivateKey  string `json:"private_key"`
+		TokenUri    string `json:"token_uri"`
+	}
+
+	err := json.Unmarshal(jsonKey, &k)
+	if err != nil {
+		return nil, fmt.Errorf("firebase: invalid service account key: %w", err)
+	}
+
+	if k.ClientEmail == "" || k.PrivateKey == "" {
+		return nil, errors.New("firebase: invalid service account key: missing client_email or private_key")
+	}
+
+	key, err := parseKey(k.PrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("firebase: invalid service account key: %w", err)
+	}
+
+	if k.TokenUri == "" {
+		k.TokenUri = defaultTokenUri
+	}
+
+	return &ServiceAccount{
+		email:    k.ClientEmail,
+		key:      key,
+		tokenUri: k.TokenUri}, nil
+}
+
+// parseKey parses a PEM-encoded RSA private key.
+func parseKey(s string) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode([]byte(s))
+	if block == nil {
+		return nil, errors.New("private key is not PEM encoded")
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("private key is not an RSA key")
+	}
+
+	return rsaKey, nil
+}
+
+// Token returns a valid access token, obtaining a new one if needed.
+func (s *ServiceAccount) Token() (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.token != "" && time.Now().Add(tokenRefreshMargin).Before(s.expiry) {
+		return s.token, nil
+	}
+
+	assertion, err := s.assertion(time.Now())
+	if err != nil {
+		return "", err
+	}
+
+	form := url.Values{
+		"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+		"assertion":  {assertion},
+	}
+
+	httpClient := s.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	res, err := httpClient.PostForm(s.tokenUri, form)
+	if err != nil {
+		return "", fmt.Errorf("firebase: cannot obtain access token: %w", err)
+	}
+	defer res.Body.Close()
+
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return "", fmt.Errorf("firebase: cannot obtain access token: %w", err)
+	}
+
+	if res.StatusCode >= 400 {
+		return "", fmt.Errorf("firebase: cannot obtain access token: %w", &APIError{StatusCode: res.StatusCode, Body: string(body)})
+	}
+
+	var t struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int64  `json:"expires_in"`
+	}
+
+	err = json.Unmarshal(body, &t)
+	if err != nil || t.AccessToken == "" {
+		return "", fmt.Errorf("firebase: cannot obtain access token: unexpected response %q", body)
+	}
+
+	s.token = t.AccessToken
+	s.expiry = time.Now().Add(time.Duration(t.ExpiresIn) * time.Second)
+
+	return s.token, nil
+}
+
+// assertion returns the signed JWT exchanged for an access token.
+func (s *ServiceAccount) assertion(now time.Time) (string, error) {
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	claims, _ := json.Marshal(map[string]interface{}{
+		"iss":   s.email,
+		"scope": strings.Join(scopes, " "),
+		"aud":   s.tokenUri,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	})
+
+	enc := base64.RawURLEncoding
+	unsigned := enc.EncodeToString(header) + "." + enc.EncodeToString(claims)
+
+	h := sha256.Sum256([]byte(unsigned))
+
+	sig, err := rsa.SignPKCS1v15(rand.Reader, s.key, crypto.SHA256, h[:])
+	if err != nil {
+		return "", fmt.Errorf("firebase: cannot sign token request: %w", err)
+	}
+
+	return unsigned + "." + enc.EncodeToString(sig), nil
+}
+
+// UseServiceAccount authenticates all calls with OAuth2 access tokens minted
+// from the given service account JSON key, instead of Auth. It sets
+// TokenSource and BearerAuth, since access tokens must be sent in an
+// Authorization header.
+func (f *F) UseServiceAccount(jsonKey []byte) error {
+	s, err := NewServiceAccount(jsonKey)
+	if err != nil {
+		return err
+	}
+
+	s.HTTPClient = f.HTTPClient
+	f.TokenSource = s
+	f.BearerAuth = true
+
+	return nil
+}
+
+// TokenSource provides the credentials used to authenticate calls.
+// Implementations are called before every request and are responsible for
+// caching and refreshing tokens, so they must be safe for concurrent use.
+type TokenSource interface {
+	Token() (string, error)
+}
+
+// StaticTokenSource is a TokenSource that always returns the same token.
+type StaticTokenSource string
+
+// Token returns the static token.
+func (s StaticTokenSource) Token() (string, error) {
+	return string(s), nil
+}
+
+// SetAuth replaces the token used by subsequent calls, e.g. when it expires.
+// Unlike assigning Auth, it is safe while calls are in flight. TokenSource
+// still takes precedence when set.
+func (f *F) SetAuth(token string) {
+	f.mu.Lock()
+	f.Auth = token
+	f.mu.Unlock()
+}
+
+// ClearAuth removes the token set with Auth or SetAuth, so that subsequent
+// calls are not authenticated unless TokenSource is set.
+func (f *F) ClearAuth() {
+	f.SetAuth("")
+}
+
+// As returns a reference to the same location that authenticates its calls
+// with token instead of the credentials of f, which are left unchanged. The
+// token is sent like Auth, in the auth query parameter or in an
+// Authorization header with BearerAuth, so it does not need to be passed in
+// the params of each call. References derived from the returned one, such
+// as its children, keep using token.
+//
+// OnAuthError is not called for the returned reference, since refreshing the
+// credentials of f would not change token.
+func (f *F) As(token string) *F {
+	ret := f.Clone()
+	ret.Auth = token
+	ret.TokenSource = nil
+	ret.OnAuthError = nil
+	ret.idToken = false
+
+	return ret
+}
+
+// AsUser returns a reference to the same location that reads and writes
+// data on behalf of the user signed in with the given Firebase ID token,
+// as obtained by a client app from Firebase Authentication. Calls are
+// allowed or denied by the security rules of the database, where auth is
+// the decoded token, and fail with ErrUnauthorized when denied. Unlike
+// access tokens, ID tokens are always sent in the auth query parameter,
+// even with BearerAuth, and to the emulator, where they can be unsigned.
+//
+// The token is only kept by the returned reference and the ones derived
+// from it, so references for different users can be used concurrently.
+// ID tokens expire after an hour; AsUser must then be called again with a
+// fresh token.
+func (f *F) AsUser(idToken string) *F {
+	ret := f.As(idToken)
+	ret.idToken = true
+
+	return ret
+}
+
+// auth returns Auth, which may be changed concurrently by SetAuth.
+func (f *F) auth() string {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	return f.Auth
+}
+
+// reauth reports whether a call failing with err should be made again, once
+// OnAuthError had a chance to refresh the credential.
+func (f *F) reauth(err error) bool {
+	return f.OnAuthError != nil && errors.Is(err, ErrUnauthorized) && f.OnAuthError(err)
+}
+
+// credential returns the credential to use for a call, from TokenSource if
+// set or Auth otherwise. TokenSource is not used with the emulator, which the
+// built-in client calls as its owner, so that no token is obtained offline.
+func (f *F) credential() (string, error) {
+	if f.TokenSource == nil || (f.EmulatorHost != "" && f.api == nil) {
+		return f.auth(), nil
+	}
+
+	token, err := f.TokenSource.Token()
+	if err != nil {
+		return "", fmt.Errorf("firebase: cannot obtain token: %w", err)
+	}
+
+	return token, nil
+}
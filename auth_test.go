@@ -0,0 +1,281 @@
+package firebase
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestServiceAccount(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+
+	der, _ := x509.MarshalPKCS8PrivateKey(key)
+	pemKey := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+
+	var tokens int
+
+	oauth := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		parts := strings.Split(r.FormValue("assertion"), ".")
+		if len(parts) != 3 {
+			t.Errorf("Unexpected assertion %q\n", r.FormValue("assertion"))
+		}
+
+		sig, _ := base64.RawURLEncoding.DecodeString(parts[2])
+		h := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+		if err := rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, h[:], sig); err != nil {
+			t.Errorf("Invalid assertion signature: %v\n", err)
+		}
+
+		tokens++
+		w.Write([]byte(`{"access_token":"access","expires_in":3600,"token_type":"Bearer"}`))
+	}))
+	defer oauth.Close()
+
+	db := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer access" || r.URL.Query().Get("auth") != "" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`"ok"`))
+	}))
+	defer db.Close()
+
+	jsonKey, _ := json.Marshal(map[string]string{
+		"type":         "service_account",
+		"client_email": "robot@example.iam.gserviceaccount.com",
+		"private_key":  string(pemKey),
+		"token_uri":    oauth.URL,
+	})
+
+	client := new(F)
+	client.Init(db.URL, "legacy", nil)
+
+	if err := client.UseServiceAccount(jsonKey); err != nil {
+		t.Fatalf("%v\n", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := client.ChildE("status", nil, nil); err != nil {
+			t.Fatalf("%v\n", err)
+		}
+	}
+
+	if tokens != 1 {
+		t.Fatalf("Expected the access token to be cached, got %d token requests\n", tokens)
+	}
+}
+
+func TestBearerAuth(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" || r.URL.Query().Get("auth") != "" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`"ok"`))
+	}))
+	defer ts.Close()
+
+	client := new(F)
+	client.Init(ts.URL, "token", nil)
+
+	if _, err := client.ChildE("status", nil, nil); err == nil {
+		t.Fatalf("Expected the token to be sent in the query string\n")
+	}
+
+	client.BearerAuth = true
+
+	if _, err := client.ChildE("status", nil, nil); err != nil {
+		t.Fatalf("%v\n", err)
+	}
+}
+
+type countingTokenSource int
+
+func (c *countingTokenSource) Token() (string, error) {
+	*c++
+	return fmt.Sprintf("token-%d", *c), nil
+}
+
+func TestTokenSource(t *testing.T) {
+	var tokens []string
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tokens = append(tokens, r.URL.Query().Get("auth"))
+		w.Write([]byte(`"ok"`))
+	}))
+	defer ts.Close()
+
+	client := new(F)
+	client.Init(ts.URL, "static", nil)
+	client.TokenSource = new(countingTokenSource)
+
+	client.Child("a", nil, nil).Child("b", nil, nil)
+
+	client.TokenSource = StaticTokenSource("static-source")
+	client.Child("c", nil, nil)
+
+	if strings.Join(tokens, ",") != "token-1,token-2,static-source" {
+		t.Fatalf("Unexpected tokens %v\n", tokens)
+	}
+}
+
+func TestSetAuth(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`"` + r.URL.Query().Get("auth") + `"`))
+	}))
+	defer ts.Close()
+
+	client := new(F)
+	client.Init(ts.URL, "old", nil)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			client.Child("data", nil, nil)
+		}()
+	}
+
+	client.SetAuth("new")
+	wg.Wait()
+
+	if v := client.Child("data", nil, nil).Value(); v != "new" {
+		t.Fatalf("Expected the new token to be sent, got %v\n", v)
+	}
+
+	client.ClearAuth()
+
+	if v := client.Child("data", nil, nil).Value(); v != "" {
+		t.Fatalf("Expected no token to be sent, got %v\n", v)
+	}
+}
+
+func TestOnAuthError(t *testing.T) {
+	calls := 0
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if r.URL.Query().Get("auth") != "fresh" {
+			http.Error(w, `{"error":"Auth token is expired"}`, http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`"ok"`))
+	}))
+	defer ts.Close()
+
+	client := new(F)
+	client.Init(ts.URL, "expired", nil)
+	client.Retry = &RetryPolicy{MaxRetries: 3}
+
+	if _, err := client.ChildE("data", nil, nil); !errors.Is(err, ErrUnauthorized) || calls != 1 {
+		t.Fatalf("Expected ErrUnauthorized without retries, got %v after %d calls\n", err, calls)
+	}
+
+	refreshes := 0
+	client.OnAuthError = func(err error) bool {
+		refreshes++
+		client.SetAuth("fresh")
+		return true
+	}
+
+	if v := client.Child("data", nil, nil).Value(); v != "ok" || refreshes != 1 {
+		t.Fatalf("Expected the call to be reissued once, got %v after %d refreshes\n", v, refreshes)
+	}
+
+	client.SetAuth("expired")
+	client.OnAuthError = func(err error) bool {
+		refreshes++
+		return true
+	}
+
+	if _, err := client.ChildE("data", nil, nil); !errors.Is(err, ErrUnauthorized) || refreshes != 2 {
+		t.Fatalf("Expected a single reissue, got %v after %d refreshes\n", err, refreshes)
+	}
+}
+
+func TestAs(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`"` + r.URL.Query().Get("auth") + r.Header.Get("Authorization") + `"`))
+	}))
+	defer ts.Close()
+
+	client := new(F)
+	client.Init(ts.URL, "admin", nil)
+	client.TokenSource = StaticTokenSource("source")
+
+	user := client.As("user")
+
+	if v := user.Child("data", nil, nil).Value(); v != "user" {
+		t.Fatalf("Expected the overriding token to be sent, got %v\n", v)
+	}
+
+	if v := user.Ref("data").Child("nested", nil, nil).Value(); v != "user" {
+		t.Fatalf("Expected derived references to keep the token, got %v\n", v)
+	}
+
+	if v := client.Child("data", nil, nil).Value(); v != "source" {
+		t.Fatalf("Expected the original credentials to be unchanged, got %v\n", v)
+	}
+
+	client.BearerAuth = true
+
+	if v := client.As("access").Child("data", nil, nil).Value(); v != "Bearer access" {
+		t.Fatalf("Expected the token to be sent in a header, got %v\n", v)
+	}
+}
+
+func TestAsUser(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`"` + r.URL.Query().Get("auth") + r.Header.Get("Authorization") + `"`))
+	}))
+	defer ts.Close()
+
+	client := new(F)
+	client.Init(ts.URL, "", nil)
+	client.TokenSource = StaticTokenSource("access")
+	client.BearerAuth = true
+
+	var wg sync.WaitGroup
+	for _, token := range []string{"alice", "bob"} {
+		wg.Add(1)
+		go func(token string) {
+			defer wg.Done()
+
+			if v := client.AsUser(token).Ref("users").Child(token, nil, nil).Value(); v != token {
+				t.Errorf("Expected the ID token in the query string, got %v\n", v)
+			}
+		}(token)
+	}
+	wg.Wait()
+
+	if v := client.Child("data", nil, nil).Value(); v != "Bearer access" {
+		t.Fatalf("Expected the original credentials to be unchanged, got %v\n", v)
+	}
+
+	// ID tokens are sent to the emulator instead of the owner credential
+	client.EmulatorHost = strings.TrimPrefix(ts.URL, "http://")
+
+	if v := client.AsUser("alice").Child("data", nil, nil).Value(); v != "alice" {
+		t.Fatalf("Expected the ID token to be sent to the emulator, got %v\n", v)
+	}
+
+	if v := client.AsUser("alice").As("access").Child("data", nil, nil).Value(); v != "Bearer owner" {
+		t.Fatalf("Expected As to replace the ID token, got %v\n", v)
+	}
+}
@@ -0,0 +1,141 @@
+package firebase
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Batch accumulates writes under a location to apply them atomically with a
+// single multi-location update, which saves a request per write for bulk
+// operations. It is created by NewBatch and is not safe for concurrent use.
+//
+//	b := f.NewBatch()
+//	b.Set("users/jack/name", "Jack").Delete("users/will")
+//	err := b.Commit(ctx)
+type Batch struct {
+	f      *F
+	writes map[string]interface{}
+	err    error
+}
+
+// NewBatch returns an empty batch of writes at paths relative to f.
+func (f *F) NewBatch() *Batch {
+	return &Batch{f: f, writes: map[string]interface{}{}}
+}
+
+// Set queues writing value at the given path, replacing a previous write at
+// the same path. The path must be below the location of the batch; the first
+// invalid path is reported by Commit.
+func (b *Batch) Set(path string, value interface{}) *Batch {
+	if key, ok := b.key(path); ok {
+		b.writes[key] = value
+	}
+
+	return b
+}
+
+// Delete queues deleting the data at the given path.
+func (b *Batch) Delete(path string) *Batch {
+	return b.Set(path, nil)
+}
+
+// Len returns the number of queued writes.
+func (b *Batch) Len() int {
+	return len(b.writes)
+}
+
+// key returns the normalized form of path, recording an error for the paths
+// that are not strictly below the location of the batch.
+func (b *Batch) key(path string) (string, bool) {
+	var segs []string
+	for _, s := range strings.Split(path, "/") {
+		switch s {
+		case "":
+			continue
+		case "..":
+			b.fail(fmt.Errorf("firebase: invalid batch path %q: paths must be relative to %s", path, b.f.Url))
+			return "", false
+		}
+		segs = append(segs, s)
+	}
+
+	if len(segs) == 0 {
+		b.fail(fmt.Errorf("firebase: invalid batch path %q: empty path", path))
+		return "", false
+	}
+
+	return strings.Join(segs, "/"), true
+}
+
+// fail records the first error of the batch.
+func (b *Batch) fail(err error) {
+	if b.err == nil {
+		b.err = err
+	}
+}
+
+// Commit applies the queued writes atomically, with a single update at the
+// deepest location containing all of them, and empties the batch on
+// success. Nothing is written if any of the writes is invalid, e.g. when
+// one of them is below another, which Firebase rejects. Committing an empty
+// batch does nothing.
+func (b *Batch) Commit(ctx context.Context) error {
+	if b.err != nil {
+		return b.err
+	}
+
+	if len(b.writes) == 0 {
+		return nil
+	}
+
+	var common []string
+	min := -1
+
+	for key := range b.writes {
+		segs := strings.Split(key, "/")
+
+		// a write below another would be overwritten by it
+		for i := 1; i < len(segs); i++ {
+			if _, ok := b.writes[strings.Join(segs[:i], "/")]; ok {
+				return fmt.Errorf("firebase: invalid batch: %q is below another write", key)
+			}
+		}
+
+		if min < 0 {
+			common, min = segs, len(segs)
+			continue
+		}
+
+		n := 0
+		for n < len(common) && n < len(segs) && common[n] == segs[n] {
+			n++
+		}
+		common = common[:n]
+
+		if len(segs) < min {
+			min = len(segs)
+		}
+	}
+
+	// each write needs a key below the location of the update
+	if len(common) >= min {
+		common = common[:min-1]
+	}
+
+	prefix := strings.Join(common, "/")
+
+	updates := make(map[string]interface{}, len(b.writes))
+	for key, v := range b.writes {
+		updates[strings.TrimPrefix(strings.TrimPrefix(key, prefix), "/")] = v
+	}
+
+	ref := b.f.derive(b.f.url(prefix))
+	if err := ref.UpdateContext(ctx, "", updates, nil); err != nil {
+		return err
+	}
+
+	b.writes = map[string]interface{}{}
+
+	return nil
+}
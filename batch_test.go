@@ -0,0 +1,111 @@
+package firebase
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestBatch(t *testing.T) {
+	fake := NewFake()
+	fake.Seed("app", map[string]interface{}{
+		"users": map[string]interface{}{"will": "Will", "jack": map[string]interface{}{"age": 30}},
+	})
+
+	client := new(F)
+	client.Init("https://example.firebaseio.com/app", "", fake)
+
+	b := client.NewBatch()
+	b.Set("users/jack/name", "Jack").Set("/users//ann/", "Ann").Delete("users/will")
+
+	if b.Len() != 3 {
+		t.Fatalf("Expected 3 writes, got %d\n", b.Len())
+	}
+
+	if err := b.Commit(context.Background()); err != nil {
+		t.Fatalf("%v\n", err)
+	}
+
+	expected := map[string]interface{}{
+		"jack": map[string]interface{}{"age": float64(30), "name": "Jack"},
+		"ann":  "Ann",
+	}
+
+	if v := fake.Data("app/users"); !reflect.DeepEqual(v, expected) {
+		t.Fatalf("Expected %v, got %v\n", expected, v)
+	}
+
+	if b.Len() != 0 {
+		t.Fatalf("Expected the batch to be emptied\n")
+	}
+}
+
+func TestBatchAncestor(t *testing.T) {
+	var requests []string
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		b, _ := io.ReadAll(r.Body)
+		json.Unmarshal(b, &body)
+
+		keys := make([]string, 0, len(body))
+		for k := range body {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		requests = append(requests, r.Method+" "+r.URL.Path+" "+strings.Join(keys, ","))
+		w.Write(b)
+	}))
+	defer ts.Close()
+
+	client := new(F)
+	client.Init(ts.URL, "", nil)
+
+	for _, test := range []struct {
+		paths    []string
+		expected string
+	}{
+		{[]string{"users/a/name", "users/b/name"}, "PATCH /users.json a/name,b/name"},
+		{[]string{"users/a/name", "users/a/age"}, "PATCH /users/a.json age,name"},
+		{[]string{"users/a/name"}, "PATCH /users/a.json name"},
+		{[]string{"users/a", "posts/b"}, "PATCH /.json posts/b,users/a"},
+	} {
+		requests = nil
+
+		b := client.NewBatch()
+		for _, p := range test.paths {
+			b.Set(p, 1)
+		}
+
+		if err := b.Commit(context.Background()); err != nil {
+			t.Fatalf("%v\n", err)
+		}
+
+		if len(requests) != 1 || requests[0] != test.expected {
+			t.Fatalf("Expected a single %q, got %q\n", test.expected, requests)
+		}
+	}
+
+	requests = nil
+
+	for _, b := range []*Batch{
+		client.NewBatch().Set("users/a", 1).Set("users/a/name", 2),
+		client.NewBatch().Set("../other", 1),
+		client.NewBatch().Set("", 1),
+	} {
+		if err := b.Commit(context.Background()); err == nil {
+			t.Fatalf("Expected an error for an invalid batch\n")
+		}
+	}
+
+	if err := client.NewBatch().Commit(context.Background()); err != nil || len(requests) != 0 {
+		t.Fatalf("Expected invalid and empty batches not to be sent, got %q\n", requests)
+	}
+}
@@ -0,0 +1,105 @@
+package firebase
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+)
+
+// Cache is an in-memory cache of values and their ETags, used by
+// CachedChild to avoid downloading data that has not changed.
+// It is safe for concurrent use.
+type Cache struct {
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+}
+
+// cacheEntry is a cached value along with its ETag.
+type cacheEntry struct {
+	etag  string
+	value interface{}
+}
+
+// NewCache returns an empty cache.
+func NewCache() *Cache {
+	return &Cache{entries: map[string]cacheEntry{}}
+}
+
+// get returns the entry cached for the given key.
+func (c *Cache) get(key string) (cacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[key]
+
+	return e, ok
+}
+
+// put caches the entry for the given key.
+func (c *Cache) put(key string, e cacheEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.entries == nil {
+		c.entries = map[string]cacheEntry{}
+	}
+
+	c.entries[key] = e
+}
+
+// CachedChild is like ChildE but revalidates the value cached in Cache, if
+// any, with a conditional request. It reports whether the value was served
+// from the cache because it had not changed. Without a Cache it always
+// downloads the value.
+func (f *F) CachedChild(path string, params map[string]string) (*F, bool, error) {
+	u := f.url(path)
+
+	key := u
+	if qs := f.withParams(params); len(qs) > 0 {
+		v := url.Values{}
+		for k, p := range qs {
+			v.Set(k, p)
+		}
+		key += "?" + v.Encode()
+	}
+
+	header := http.Header{"X-Firebase-ETag": {"true"}}
+
+	var cached cacheEntry
+	var ok bool
+
+	if f.Cache != nil {
+		if cached, ok = f.Cache.get(key); ok {
+			header.Set("If-None-Match", cached.etag)
+		}
+	}
+
+	res, err := f.callHeader(context.Background(), "GET", u, nil, params, header)
+	if err != nil {
+		return nil, false, fmt.Errorf("firebase: get %s: %w", u, err)
+	}
+
+	ret := f.derive(u)
+
+	if ok && res.StatusCode == http.StatusNotModified {
+		ret.setValue(cached.value, cached.value == nil)
+		return ret, true, nil
+	}
+
+	var v interface{}
+
+	_, err = decode(res.Body, &v, f.UseNumber)
+	if err != nil {
+		return nil, false, &DecodeError{Path: u, Raw: res.Body, Err: err}
+	}
+
+	if f.Cache != nil {
+		f.Cache.put(key, cacheEntry{etag: res.Header.Get("ETag"), value: v})
+	}
+
+	ret.setValue(v, v == nil)
+
+	return ret, false, nil
+}
@@ -0,0 +1,61 @@
+package firebase
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCachedChild(t *testing.T) {
+	value := `{"a":1}`
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == value {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", value)
+		w.Write([]byte(value))
+	}))
+	defer ts.Close()
+
+	client := new(F)
+	client.Init(ts.URL, "", nil)
+	client.Cache = NewCache()
+
+	expected := []bool{false, true, false}
+
+	for i, e := range expected {
+		if i == 2 {
+			value = `{"a":2}`
+		}
+
+		r, cached, err := client.CachedChild("data", nil)
+
+		if err != nil {
+			t.Fatalf("%v\n", err)
+		}
+
+		if cached != e {
+			t.Fatalf("Expected cached to be %v for call %d\n", e, i)
+		}
+
+		if r.Value() == nil {
+			t.Fatalf("Expected a value for call %d\n", i)
+		}
+	}
+
+	if r, _, _ := client.CachedChild("data", nil); r.Value().(map[string]interface{})["a"] != float64(2) {
+		t.Fatalf("Expected the updated value, got %v\n", r.Value())
+	}
+}
+
+func TestCacheShared(t *testing.T) {
+	client := new(F)
+	client.Init("https://example.firebaseio.com", "", nil)
+	client.Cache = NewCache()
+
+	if r := client.Ref("users").Parent(); r.Cache != client.Cache {
+		t.Fatalf("Expected derived references to share the cache\n")
+	}
+}
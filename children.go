@@ -0,0 +1,147 @@
+package firebase
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// Children returns references to the immediate children at the given path,
+// each populated with its value, so that a collection can be iterated over
+// with a single call. Children follow the orderBy of params, which is applied
+// by the client as Firebase does not sort its responses; otherwise they are
+// in the order Firebase sent them. A missing or null node has no children.
+func (f *F) Children(path string, params map[string]string) ([]*F, error) {
+	u := f.url(path)
+
+	kvs, err := f.orderedChildren(context.Background(), u, params)
+	if err != nil {
+		return nil, err
+	}
+
+	parent := f.derive(u)
+
+	ret := make([]*F, len(kvs))
+	for i, kv := range kvs {
+		ret[i] = f.derive(parent.url(kv.Key))
+		ret[i].setValue(kv.Value, false)
+	}
+
+	return ret, nil
+}
+
+// KV is a child key along with its value, as returned by OrderedChildren.
+type KV struct {
+	Key   string
+	Value interface{}
+}
+
+// OrderedChildren returns the keys and values of the immediate children at
+// the given path matching q. Unlike the maps returned by Child, the children
+// are in the order of the orderBy of q, which Firebase does not guarantee in
+// its responses and which is therefore applied by the client. q may be nil
+// to read all the children, in the order Firebase sent them.
+func (f *F) OrderedChildren(path string, q *Query) ([]KV, error) {
+	var params map[string]string
+	if q != nil {
+		if err := q.Err(); err != nil {
+			return nil, err
+		}
+		params = q.Params()
+	}
+
+	return f.orderedChildren(context.Background(), f.url(path), params)
+}
+
+// orderedChildren reads the children at the Firebase URL u, sorted by the
+// orderBy parameter if any.
+func (f *F) orderedChildren(ctx context.Context, u string, params map[string]string) ([]KV, error) {
+	res, err := f.call(ctx, "GET", u, nil, params)
+	if err != nil {
+		return nil, fmt.Errorf("firebase: get %s: %w", u, err)
+	}
+
+	keys, values, err := decodeChildren(bytes.NewReader(res), f.UseNumber)
+	if err != nil {
+		return nil, &DecodeError{Path: u, Raw: res, Err: err}
+	}
+
+	ret := make([]KV, len(keys))
+	for i, k := range keys {
+		ret[i] = KV{Key: k, Value: values[i]}
+	}
+
+	var child string
+	if err := json.Unmarshal([]byte(params["orderBy"]), &child); err == nil {
+		sortChildren(ret, child)
+	}
+
+	return ret, nil
+}
+
+// decodeChildren decodes the JSON object read from r into its keys and
+// values, keeping the order in which they appear. Arrays, which Firebase
+// returns for children with sequential numeric keys, are decoded as if keyed
+// by their indexes, leaving out null entries. Numbers are decoded as
+// json.Number with useNumber.
+func decodeChildren(r io.Reader, useNumber bool) ([]string, []interface{}, error) {
+	dec := json.NewDecoder(r)
+	if useNumber {
+		dec.UseNumber()
+	}
+
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var keys []string
+	var values []interface{}
+
+	switch tok {
+	case nil:
+		return nil, nil, nil
+
+	case json.Delim('{'):
+		for dec.More() {
+			tok, err := dec.Token()
+			if err != nil {
+				return nil, nil, err
+			}
+
+			var v interface{}
+			if err := dec.Decode(&v); err != nil {
+				return nil, nil, err
+			}
+
+			keys = append(keys, tok.(string))
+			values = append(values, v)
+		}
+
+	case json.Delim('['):
+		for i := 0; dec.More(); i++ {
+			var v interface{}
+			if err := dec.Decode(&v); err != nil {
+				return nil, nil, err
+			}
+
+			if v != nil {
+				keys = append(keys, strconv.Itoa(i))
+				values = append(values, v)
+			}
+		}
+
+	default:
+		return nil, nil, fmt.Errorf("firebase: expected an object, got %v", tok)
+	}
+
+	// consume the closing delimiter
+	if _, err := dec.Token(); err != nil {
+		return nil, nil, err
+	}
+
+	return keys, values, nil
+}
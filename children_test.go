@@ -0,0 +1,106 @@
+package firebase
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestChildren(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/users.json":
+			w.Write([]byte(`{"zed":{"age":40},"amy":{"age":20},"max":{"age":30}}`))
+		case "/list.json":
+			w.Write([]byte(`["a",null,"c"]`))
+		default:
+			w.Write([]byte(`null`))
+		}
+	}))
+	defer ts.Close()
+
+	client := new(F)
+	client.Init(ts.URL, "", nil)
+
+	children, err := client.Children("users", nil)
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+
+	var keys []string
+	for _, c := range children {
+		keys = append(keys, c.Key())
+	}
+
+	if expected := []string{"zed", "amy", "max"}; !reflect.DeepEqual(keys, expected) {
+		t.Fatalf("Expected keys %v, got %v\n", expected, keys)
+	}
+
+	if c := children[1]; c.Url != ts.URL+"/users/amy" || c.Value().(map[string]interface{})["age"] != float64(20) {
+		t.Fatalf("Unexpected child %s with value %v\n", c.Url, c.Value())
+	}
+
+	children, err = client.Children("users", NewQuery().OrderByChild("age").Params())
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+
+	keys = nil
+	for _, c := range children {
+		keys = append(keys, c.Key())
+	}
+
+	if expected := []string{"amy", "max", "zed"}; !reflect.DeepEqual(keys, expected) {
+		t.Fatalf("Expected keys ordered by age %v, got %v\n", expected, keys)
+	}
+
+	children, err = client.Children("list", nil)
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+
+	if len(children) != 2 || children[1].Key() != "2" || children[1].Value() != "c" {
+		t.Fatalf("Expected the non-null array entries, got %d children\n", len(children))
+	}
+
+	children, err = client.Children("missing", nil)
+	if err != nil || len(children) != 0 {
+		t.Fatalf("Expected no children, got %d and %v\n", len(children), err)
+	}
+}
+
+func TestDecodeChildrenNotObject(t *testing.T) {
+	if _, _, err := decodeChildren(strings.NewReader(`"value"`), false); err == nil {
+		t.Fatalf("Expected an error for a scalar value\n")
+	}
+}
+
+func TestOrderedChildren(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("orderBy") != `"age"` || r.URL.Query().Get("limitToFirst") != "3" {
+			t.Errorf("Unexpected query %s\n", r.URL.RawQuery)
+		}
+		// Firebase does not sort the children of REST responses
+		w.Write([]byte(`{"amy":{"age":40},"zed":{"age":20},"max":{"age":30}}`))
+	}))
+	defer ts.Close()
+
+	client := new(F)
+	client.Init(ts.URL, "", nil)
+
+	kvs, err := client.OrderedChildren("ages", NewQuery().OrderByChild("age").LimitToFirst(3))
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+
+	var keys []string
+	for _, kv := range kvs {
+		keys = append(keys, kv.Key)
+	}
+
+	if expected := []string{"zed", "max", "amy"}; !reflect.DeepEqual(keys, expected) {
+		t.Fatalf("Expected %v, got %v\n", expected, keys)
+	}
+}
@@ -0,0 +1,131 @@
+package firebase
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// ErrNotFound is reported when Firebase responds with 404 Not Found.
+// Errors returned by calls can be checked with errors.Is(err, ErrNotFound).
+var ErrNotFound = errors.New("firebase: not found")
+
+// ErrETagMismatch is reported when a conditional request fails because the
+// data changed since its ETag was read. Errors returned by calls can be
+// checked with errors.Is(err, ErrETagMismatch).
+var ErrETagMismatch = errors.New("firebase: etag mismatch")
+
+// ErrAlreadyExists is returned by SetIfAbsent when there is already data at
+// the given path.
+var ErrAlreadyExists = errors.New("firebase: already exists")
+
+// ErrNotNumeric is returned by Increment when the value to increment is not
+// a number.
+var ErrNotNumeric = errors.New("firebase: value is not a number")
+
+// ErrUnauthorized is reported when Firebase responds with 401 Unauthorized
+// or 403 Forbidden, e.g. because the token expired or the security rules
+// deny access. Such calls are never retried, unlike 5xx responses.
+var ErrUnauthorized = errors.New("firebase: unauthorized")
+
+// ErrUnsupported is returned by methods that need request or response
+// headers, which are only available with the built-in client.
+var ErrUnsupported = errors.New("firebase: operation not supported by this Api")
+
+// ErrSilentPush is returned when Push is called with print=silent, since
+// Push needs the response to learn the generated key.
+var ErrSilentPush = errors.New("firebase: push cannot use print=silent")
+
+// ErrRateLimited is returned when a call cannot be made before the deadline
+// of its context because of the RateLimiter of the client.
+var ErrRateLimited = errors.New("firebase: rate limit exceeded")
+
+// ErrResponseTooLarge is returned when a response exceeds MaxResponseBytes.
+var ErrResponseTooLarge = errors.New("firebase: response too large")
+
+// ErrInvalidKey is reported when a value to write has a key that Firebase
+// would reject, see Validate.
+var ErrInvalidKey = errors.New("firebase: invalid key")
+
+// DecodeError is returned when a response cannot be decoded, e.g. because
+// Firebase returned an unexpected shape for the value asked for. Use
+// errors.As to tell it apart from a failed call.
+type DecodeError struct {
+	// Path is the Firebase URL that was read.
+	Path string
+
+	// Raw is the response that failed to decode. It is nil when the response
+	// was decoded as it was received, see StreamDecode.
+	Raw []byte
+
+	// Err is the underlying decoding error.
+	Err error
+}
+
+// Error returns a human-readable description of the error.
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("firebase: decode %s: %v", e.Path, e.Err)
+}
+
+// Unwrap returns the underlying decoding error.
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// APIError is returned when Firebase responds with an error status code.
+// Use errors.As to inspect the status and message of a failed call.
+type APIError struct {
+	// StatusCode is the HTTP status code of the response.
+	StatusCode int
+
+	// Message is the error message sent by Firebase as {"error": message},
+	// such as "Permission denied" when the security rules reject a call, or
+	// the raw body of responses of another shape.
+	Message string
+
+	// Body is the raw body of the response.
+	Body string
+}
+
+// newAPIError returns the error for a response with the given status and
+// body, parsing the message of the standard Firebase error body.
+func newAPIError(statusCode int, body string) *APIError {
+	e := &APIError{StatusCode: statusCode, Message: strings.TrimSpace(body), Body: body}
+
+	var r struct {
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal([]byte(body), &r); err == nil && r.Error != "" {
+		e.Message = r.Error
+	}
+
+	return e
+}
+
+// Error returns a human-readable description of the error.
+func (e *APIError) Error() string {
+	msg := e.Message
+	if msg == "" {
+		msg = e.Body
+	}
+
+	return fmt.Sprintf("firebase: %d %s: %s", e.StatusCode, http.StatusText(e.StatusCode), msg)
+}
+
+// Is reports whether the error matches target, so that a 404 response
+// matches ErrNotFound, a 412 response matches ErrETagMismatch, and 401 and
+// 403 responses match ErrUnauthorized.
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrETagMismatch:
+		return e.StatusCode == http.StatusPreconditionFailed
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden
+	}
+
+	return false
+}
@@ -0,0 +1,109 @@
+package firebase
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAPIError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"error":"Permission denied"}`, http.StatusUnauthorized)
+	}))
+	defer ts.Close()
+
+	client := new(F)
+	client.Init(ts.URL, "", nil)
+
+	err := client.Remove("users", nil)
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("Expected an *APIError, got %v\n", err)
+	}
+
+	if apiErr.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("Expected status %d, got %d\n", http.StatusUnauthorized, apiErr.StatusCode)
+	}
+
+	if apiErr.Message != "Permission denied" || err.Error() != "firebase: 401 Unauthorized: Permission denied" {
+		t.Fatalf("Expected the message to be parsed, got %q\n", err)
+	}
+}
+
+func TestAPIErrorMessage(t *testing.T) {
+	for body, expected := range map[string]string{
+		`{"error" : "Permission denied"}`: "Permission denied",
+		"Service Unavailable\n":           "Service Unavailable",
+		`{"message":"other"}`:             `{"message":"other"}`,
+		`{"error":{"code":1}}`:            `{"error":{"code":1}}`,
+		"":                                "",
+	} {
+		if e := newAPIError(http.StatusBadRequest, body); e.Message != expected || e.Body != body {
+			t.Errorf("Expected message %q for %q, got %q\n", expected, body, e.Message)
+		}
+	}
+
+	if e := (&APIError{StatusCode: http.StatusBadRequest, Body: "raw"}); e.Error() != "firebase: 400 Bad Request: raw" {
+		t.Fatalf("Expected the body without a message, got %q\n", e.Error())
+	}
+}
+
+func TestErrNotFound(t *testing.T) {
+	ts := httptest.NewServer(http.NotFoundHandler())
+	defer ts.Close()
+
+	client := new(F)
+	client.Init(ts.URL, "", nil)
+
+	r, err := client.ChildE("missing", nil, nil)
+
+	if r != nil {
+		t.Fatalf("Expected no child to be returned\n")
+	}
+
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Expected ErrNotFound, got %v\n", err)
+	}
+}
+
+func TestErrUnauthorized(t *testing.T) {
+	for _, code := range []int{http.StatusUnauthorized, http.StatusForbidden} {
+		if err := error(&APIError{StatusCode: code}); !errors.Is(err, ErrUnauthorized) {
+			t.Errorf("Expected %d to match ErrUnauthorized\n", code)
+		}
+	}
+
+	if err := error(&APIError{StatusCode: http.StatusInternalServerError}); errors.Is(err, ErrUnauthorized) {
+		t.Errorf("Expected 500 not to match ErrUnauthorized\n")
+	}
+}
+
+func TestDecodeError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"error":"unexpected"}`))
+	}))
+	defer ts.Close()
+
+	client := new(F)
+	client.Init(ts.URL, "", nil)
+
+	var n int
+	_, err := client.ChildE("count", nil, &n)
+
+	var decodeErr *DecodeError
+	if !errors.As(err, &decodeErr) {
+		t.Fatalf("Expected a DecodeError, got %v\n", err)
+	}
+
+	if decodeErr.Path != ts.URL+"/count" || string(decodeErr.Raw) != `{"error":"unexpected"}` {
+		t.Fatalf("Unexpected error %+v\n", decodeErr)
+	}
+
+	client.StreamDecode = true
+
+	if _, err := client.ChildE("count", nil, &n); !errors.As(err, &decodeErr) || decodeErr.Raw != nil {
+		t.Fatalf("Expected a DecodeError without the response, got %v\n", err)
+	}
+}
@@ -0,0 +1,123 @@
+package firebase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// GetWithETag returns the value at the given path along with its ETag, which
+// identifies the current version of the data and can be passed to SetIfMatch.
+func (f *F) GetWithETag(path string) (interface{}, string, error) {
+	u := f.url(path)
+
+	header := http.Header{"X-Firebase-ETag": {"true"}}
+
+	res, err := f.callHeader(context.Background(), "GET", u, nil, nil, header)
+	if err != nil {
+		return nil, "", fmt.Errorf("firebase: get %s: %w", u, err)
+	}
+
+	var v interface{}
+
+	_, err = decode(res.Body, &v, f.UseNumber)
+	if err != nil {
+		return nil, "", &DecodeError{Path: u, Raw: res.Body, Err: err}
+	}
+
+	return v, res.Header.Get("ETag"), nil
+}
+
+// SetIfMatch is like Set but only writes the value if the data at the given
+// path still has the given ETag. Otherwise the returned error matches
+// ErrETagMismatch and nothing is written.
+func (f *F) SetIfMatch(path string, value interface{}, etag string) (*F, error) {
+	u := f.url(path)
+
+	body, err := f.marshal(u, value, false)
+	if err != nil {
+		return nil, err
+	}
+
+	header := http.Header{"If-Match": {etag}}
+
+	res, err := f.callHeader(context.Background(), "PUT", u, body, nil, header)
+	if err != nil {
+		return nil, fmt.Errorf("firebase: set %s: %w", u, err)
+	}
+
+	ret := f.derive(u)
+
+	var r interface{}
+
+	_, err = decode(res.Body, &r, f.UseNumber)
+	if err != nil {
+		return nil, &DecodeError{Path: u, Raw: res.Body, Err: err}
+	}
+
+	ret.setValue(r, r == nil)
+
+	return ret, nil
+}
+
+// nullETag is the ETag Firebase gives to locations without data.
+const nullETag = "null_etag"
+
+// SetIfAbsent is like Set but only writes the value if there is no data at
+// the given path. Otherwise the returned error matches ErrAlreadyExists and
+// nothing is written. The check is atomic: the write is conditional on the
+// ETag Firebase gives to missing data, sent in an If-Match header, so
+// Firebase rejects it with 412 Precondition Failed if data was written first.
+func (f *F) SetIfAbsent(path string, value interface{}) (*F, error) {
+	ret, err := f.SetIfMatch(path, value, nullETag)
+	if errors.Is(err, ErrETagMismatch) {
+		return nil, fmt.Errorf("firebase: set %s: %w", f.url(path), ErrAlreadyExists)
+	}
+
+	return ret, err
+}
+
+// RemoveIfMatch is like Remove but only deletes the data at the given path
+// if it still has the given ETag. Otherwise the returned error matches
+// ErrETagMismatch and nothing is deleted. This includes data deleted since
+// its ETag was read, whose ETag is then the one of missing data.
+// Removing missing data with the ETag returned for it succeeds, like Remove,
+// so removals can safely be repeated.
+func (f *F) RemoveIfMatch(path, etag string, params map[string]string) error {
+	u := f.url(path)
+
+	header := http.Header{"If-Match": {etag}}
+
+	_, err := f.callHeader(context.Background(), "DELETE", u, nil, params, header)
+	if err != nil {
+		return fmt.Errorf("firebase: remove %s: %w", u, err)
+	}
+
+	return nil
+}
+
+// RemoveAndGet deletes the data at the given path and returns the value that
+// was removed, which is nil if there was nothing to remove. The removal is
+// conditional on the data not changing after it was read, and is retried
+// otherwise, so the returned value is exactly what was deleted.
+func (f *F) RemoveAndGet(path string, params map[string]string) (interface{}, error) {
+	u := f.url(path)
+
+	for i := 0; i <= MaxTransactionRetries; i++ {
+		v, etag, err := f.GetWithETag(path)
+		if err != nil {
+			return nil, err
+		}
+
+		err = f.RemoveIfMatch(path, etag, params)
+		if !errors.Is(err, ErrETagMismatch) {
+			if err != nil {
+				return nil, err
+			}
+			return v, nil
+		}
+	}
+
+	return nil, fmt.Errorf("firebase: remove %s: too many retries: %w", u, ErrETagMismatch)
+}
@@ -0,0 +1,185 @@
+package firebase
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// etagServer serves a single value whose ETag is its JSON encoding.
+func etagServer(t *testing.T, value string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET":
+			if r.Header.Get("X-Firebase-ETag") == "true" {
+				w.Header().Set("ETag", value)
+			}
+			w.Write([]byte(value))
+		case "PUT":
+			if r.Header.Get("If-Match") != value {
+				w.Header().Set("ETag", value)
+				w.WriteHeader(http.StatusPreconditionFailed)
+				w.Write([]byte(value))
+				return
+			}
+			b, _ := io.ReadAll(r.Body)
+			value = string(b)
+			w.Write(b)
+		}
+	}))
+}
+
+func TestSetIfMatch(t *testing.T) {
+	ts := etagServer(t, `1`)
+	defer ts.Close()
+
+	client := new(F)
+	client.Init(ts.URL, "", nil)
+
+	v, etag, err := client.GetWithETag("counter")
+
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+
+	if v != float64(1) || etag != `1` {
+		t.Fatalf("Unexpected value %v and etag %q\n", v, etag)
+	}
+
+	if _, err := client.SetIfMatch("counter", 2, etag); err != nil {
+		t.Fatalf("%v\n", err)
+	}
+
+	_, err = client.SetIfMatch("counter", 3, etag)
+
+	if !errors.Is(err, ErrETagMismatch) {
+		t.Fatalf("Expected ErrETagMismatch, got %v\n", err)
+	}
+}
+
+func TestRemoveAndGet(t *testing.T) {
+	value := `{"first":"Jack"}`
+	deletes := 0
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET":
+			w.Header().Set("ETag", value)
+			w.Write([]byte(value))
+		case "DELETE":
+			deletes++
+			// simulate a concurrent write before the first delete
+			if deletes == 1 {
+				value = `{"first":"John"}`
+			}
+			if r.Header.Get("If-Match") != value {
+				w.WriteHeader(http.StatusPreconditionFailed)
+				return
+			}
+			value = `null`
+			w.Write([]byte(value))
+		}
+	}))
+	defer ts.Close()
+
+	client := new(F)
+	client.Init(ts.URL, "", nil)
+
+	v, err := client.RemoveAndGet("users/jack", nil)
+
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+
+	if v.(map[string]interface{})["first"] != "John" {
+		t.Fatalf("Expected the removed value, got %v\n", v)
+	}
+
+	v, err = client.RemoveAndGet("users/jack", nil)
+
+	if err != nil || v != nil {
+		t.Fatalf("Expected nothing to be removed, got %v, %v\n", v, err)
+	}
+}
+
+func TestSetIfAbsent(t *testing.T) {
+	var value string
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		etag := "null_etag"
+		if value != "" {
+			etag = value
+		}
+
+		if r.Header.Get("If-Match") != etag {
+			w.WriteHeader(http.StatusPreconditionFailed)
+			return
+		}
+
+		b, _ := io.ReadAll(r.Body)
+		value = string(b)
+		w.Write(b)
+	}))
+	defer ts.Close()
+
+	client := new(F)
+	client.Init(ts.URL, "", nil)
+
+	r, err := client.SetIfAbsent("users/jack", "Jack")
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+
+	if r.Value() != "Jack" {
+		t.Fatalf("Expected the written value, got %v\n", r.Value())
+	}
+
+	if _, err := client.SetIfAbsent("users/jack", "John"); !errors.Is(err, ErrAlreadyExists) {
+		t.Fatalf("Expected ErrAlreadyExists, got %v\n", err)
+	}
+
+	if value != `"Jack"` {
+		t.Fatalf("Expected the value not to be overwritten, got %s\n", value)
+	}
+}
+
+func TestRemoveIfMatch(t *testing.T) {
+	value := `1`
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		etag := value
+		if value == "" {
+			etag = "null_etag"
+		}
+
+		if r.Header.Get("If-Match") != etag {
+			w.WriteHeader(http.StatusPreconditionFailed)
+			return
+		}
+
+		value = ""
+		w.Write([]byte(`null`))
+	}))
+	defer ts.Close()
+
+	client := new(F)
+	client.Init(ts.URL, "", nil)
+
+	if err := client.RemoveIfMatch("counter", `2`, nil); !errors.Is(err, ErrETagMismatch) {
+		t.Fatalf("Expected ErrETagMismatch, got %v\n", err)
+	}
+
+	if err := client.RemoveIfMatch("counter", `1`, nil); err != nil {
+		t.Fatalf("%v\n", err)
+	}
+
+	if err := client.RemoveIfMatch("counter", `1`, nil); !errors.Is(err, ErrETagMismatch) {
+		t.Fatalf("Expected ErrETagMismatch once removed, got %v\n", err)
+	}
+
+	if err := client.RemoveIfMatch("counter", "null_etag", nil); err != nil {
+		t.Fatalf("Expected removing missing data to succeed, got %v\n", err)
+	}
+}
@@ -0,0 +1,53 @@
+package firebase
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+)
+
+// Export writes the JSON value at the given path to w as it is received,
+// without holding it in memory, which suits backups of large databases.
+// The value is read with format=export so that priorities are kept and
+// restored by Import.
+//
+// Export is only supported by the built-in client. The transfer is not
+// bounded by Timeout and is not retried once started.
+func (f *F) Export(path string, w io.Writer) error {
+	u := f.url(path)
+
+	res, err := f.open(context.Background(), "GET", u, nil, NewQuery().FormatExport().Params())
+	if err != nil {
+		return fmt.Errorf("firebase: export %s: %w", u, err)
+	}
+	defer res.Body.Close()
+
+	_, err = io.Copy(w, res.Body)
+	if err != nil {
+		return fmt.Errorf("firebase: export %s: %w", u, err)
+	}
+
+	return nil
+}
+
+// Import replaces the data at the given path with the JSON read from r,
+// streaming it to Firebase as it is read. It is the counterpart of Export.
+//
+// Import is only supported by the built-in client. The transfer is not
+// bounded by Timeout and is never retried, since r cannot be read twice.
+func (f *F) Import(path string, r io.Reader) error {
+	u := f.url(path)
+
+	params := map[string]string{"print": "silent"}
+
+	res, err := f.open(context.Background(), "PUT", u, r, params)
+	if err != nil {
+		return fmt.Errorf("firebase: import %s: %w", u, err)
+	}
+	defer res.Body.Close()
+
+	io.Copy(ioutil.Discard, res.Body)
+
+	return nil
+}
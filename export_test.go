@@ -0,0 +1,105 @@
+package firebase
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestExport(t *testing.T) {
+	value := `{"users":{"a":{"name":{".value":"Ann",".priority":1}}}}`
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/backup.json" || r.URL.Query().Get("format") != "export" {
+			t.Errorf("Unexpected request %q\n", r.URL)
+		}
+		w.Write([]byte(value))
+	}))
+	defer ts.Close()
+
+	client := new(F)
+	client.Init(ts.URL, "", nil)
+
+	var buf bytes.Buffer
+
+	if err := client.Export("backup", &buf); err != nil {
+		t.Fatalf("%v\n", err)
+	}
+
+	if buf.String() != value {
+		t.Fatalf("Expected %s, got %s\n", value, buf.String())
+	}
+}
+
+func TestExportError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"error":"Permission denied"}`, http.StatusUnauthorized)
+	}))
+	defer ts.Close()
+
+	client := new(F)
+	client.Init(ts.URL, "", nil)
+
+	var buf bytes.Buffer
+
+	err := client.Export("backup", &buf)
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("Expected a 401 APIError, got %v\n", err)
+	}
+
+	if buf.Len() != 0 {
+		t.Fatalf("Expected nothing to be written, got %s\n", buf.String())
+	}
+}
+
+func TestImport(t *testing.T) {
+	value := `{"users":{"a":{"name":"Ann"}}}`
+
+	var got string
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "PUT" {
+			t.Errorf("Expected PUT, got %s\n", r.Method)
+		}
+		if r.URL.Query().Get("print") != "silent" {
+			t.Errorf("Expected print=silent, got %q\n", r.URL.RawQuery)
+		}
+		b, _ := ioutil.ReadAll(r.Body)
+		got = string(b)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+
+	client := new(F)
+	client.Init(ts.URL, "", nil)
+
+	if err := client.Import("backup", strings.NewReader(value)); err != nil {
+		t.Fatalf("%v\n", err)
+	}
+
+	if got != value {
+		t.Fatalf("Expected %s to be imported, got %s\n", value, got)
+	}
+}
+
+func TestExportUnsupported(t *testing.T) {
+	client := new(F)
+	client.Init("https://example.firebaseio.com", "", new(nullApi))
+
+	if err := client.Export("backup", ioutil.Discard); !errors.Is(err, ErrUnsupported) {
+		t.Fatalf("Expected ErrUnsupported, got %v\n", err)
+	}
+}
+
+// nullApi is an Api other than the built-in client.
+type nullApi struct{}
+
+func (nullApi) Call(method, path, auth string, body []byte, params map[string]string) ([]byte, error) {
+	return nil, nil
+}
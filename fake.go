@@ -0,0 +1,349 @@
+package firebase
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Fake is an in-memory database behaving like Firebase, for end-to-end tests
+// of code using the client without a network or the emulator. It implements
+// Api and can be given to Init:
+//
+//	fake := firebase.NewFake()
+//	fake.Seed("users/jack", map[string]string{"name": "Jack"})
+//	f := new(firebase.F)
+//	f.Init("https://example.firebaseio.com", "", fake)
+//
+// PUT replaces a value, PATCH merges the given children, POST adds a child
+// under a sortable push key, DELETE removes a value, and GET supports the
+// orderBy, startAt, endAt, equalTo, limitToFirst, limitToLast and shallow
+// parameters. The print parameter is honored by all methods. Writing null
+// removes a value and server values are resolved. A Fake is safe for
+// concurrent use.
+type Fake struct {
+	// mu guards the fields below
+	mu sync.Mutex
+
+	// data is the root of the tree
+	data interface{}
+}
+
+// NewFake returns an empty fake database.
+func NewFake() *Fake {
+	return new(Fake)
+}
+
+// Seed replaces the value at the given path, such as "users/jack", with the
+// JSON encoding of value.
+func (fk *Fake) Seed(path string, value interface{}) error {
+	b, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("firebase: marshal value for %s: %w", path, err)
+	}
+
+	var v interface{}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+
+	fk.mu.Lock()
+	fk.data = fakeSet(fk.data, fakeKeys(path), v)
+	fk.mu.Unlock()
+
+	return nil
+}
+
+// Data returns the value stored at the given path, decoded like Child does,
+// or nil if there is none. The returned value must not be modified.
+func (fk *Fake) Data(path string) interface{} {
+	fk.mu.Lock()
+	defer fk.mu.Unlock()
+
+	return valueAt(fk.data, fakeKeys(path))
+}
+
+// Call applies the given HTTP method to the value at the given Firebase URL.
+// Invalid calls fail with an *APIError with status 400 Bad Request.
+func (fk *Fake) Call(method, path, auth string, body []byte, params map[string]string) ([]byte, error) {
+	fk.mu.Lock()
+	defer fk.mu.Unlock()
+
+	keys := fakeKeys(path)
+
+	var v interface{}
+	if len(body) > 0 {
+		if err := json.Unmarshal(body, &v); err != nil {
+			return nil, badRequest("Invalid data; couldn't parse JSON object.")
+		}
+	}
+
+	var ret interface{}
+
+	switch method {
+	case "GET":
+		res, err := fakeQuery(valueAt(fk.data, keys), params)
+		if err != nil || params["print"] != "pretty" {
+			return res, err
+		}
+
+		var buf bytes.Buffer
+		if err := json.Indent(&buf, res, "", "  "); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+
+	case "PUT":
+		ret = fk.resolve(v, keys)
+		fk.data = fakeSet(fk.data, keys, ret)
+
+	case "PATCH":
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, badRequest("Invalid data; couldn't parse JSON object.")
+		}
+
+		for k, child := range m {
+			ck := append(append([]string{}, keys...), fakeKeys(k)...)
+			m[k] = fk.resolve(child, ck)
+			fk.data = fakeSet(fk.data, ck, m[k])
+		}
+		ret = m
+
+	case "POST":
+		ck := append(append([]string{}, keys...), NewPushID())
+		fk.data = fakeSet(fk.data, ck, fk.resolve(v, ck))
+		ret = map[string]string{"name": ck[len(ck)-1]}
+
+	case "DELETE":
+		fk.data = fakeSet(fk.data, keys, nil)
+
+	default:
+		return nil, &APIError{StatusCode: http.StatusMethodNotAllowed}
+	}
+
+	switch params["print"] {
+	case "silent":
+		return nil, nil
+	case "pretty":
+		return json.MarshalIndent(ret, "", "  ")
+	}
+
+	return json.Marshal(ret)
+}
+
+// badRequest returns the error Firebase responds with to invalid calls.
+func badRequest(msg string) error {
+	b, _ := json.Marshal(map[string]string{"error": msg})
+	return newAPIError(http.StatusBadRequest, string(b))
+}
+
+// fakeKeys returns the unescaped keys of a Firebase URL or path.
+func fakeKeys(path string) []string {
+	if u, err := url.Parse(path); err == nil {
+		path = u.EscapedPath()
+	}
+
+	path = strings.TrimSuffix(path, suffix)
+
+	var keys []string
+	for _, k := range strings.Split(path, "/") {
+		if k == "" {
+			continue
+		}
+
+		if uk, err := url.PathUnescape(k); err == nil {
+			k = uk
+		}
+
+		keys = append(keys, k)
+	}
+
+	return keys
+}
+
+// fakeSet returns v with the value at the given keys replaced. A nil value
+// removes the key, along with the parents left empty.
+func fakeSet(v interface{}, keys []string, value interface{}) interface{} {
+	if len(keys) == 0 {
+		return value
+	}
+
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		m = map[string]interface{}{}
+	}
+
+	child := fakeSet(m[keys[0]], keys[1:], value)
+	if child == nil {
+		delete(m, keys[0])
+	} else {
+		m[keys[0]] = child
+	}
+
+	if len(m) == 0 {
+		return nil
+	}
+
+	return m
+}
+
+// resolve replaces the server values in v, which is to be written at the
+// given keys, by their value.
+func (fk *Fake) resolve(v interface{}, keys []string) interface{} {
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return v
+	}
+
+	if sv, ok := m[".sv"]; ok && len(m) == 1 {
+		switch sv := sv.(type) {
+		case string:
+			if sv == "timestamp" {
+				return float64(time.Now().UnixNano() / int64(time.Millisecond))
+			}
+		case map[string]interface{}:
+			if delta, ok := sv["increment"].(float64); ok {
+				n, _ := valueAt(fk.data, keys).(float64)
+				return n + delta
+			}
+		}
+	}
+
+	for k, child := range m {
+		m[k] = fk.resolve(child, append(append([]string{}, keys...), k))
+	}
+
+	return m
+}
+
+// fakeQuery encodes v filtered by the query parameters. The children of
+// ordered queries are encoded in order.
+func fakeQuery(v interface{}, params map[string]string) ([]byte, error) {
+	if params["shallow"] == "true" {
+		if m, ok := v.(map[string]interface{}); ok {
+			ret := make(map[string]bool, len(m))
+			for k := range m {
+				ret[k] = true
+			}
+			return json.Marshal(ret)
+		}
+		return json.Marshal(v)
+	}
+
+	orderBy, ok := params["orderBy"]
+	if !ok {
+		for _, p := range []string{"startAt", "endAt", "equalTo", "limitToFirst", "limitToLast"} {
+			if _, ok := params[p]; ok {
+				return nil, badRequest("orderBy must be defined when other query parameters are defined")
+			}
+		}
+		return json.Marshal(v)
+	}
+
+	var child string
+	if err := json.Unmarshal([]byte(orderBy), &child); err != nil {
+		return nil, badRequest("orderBy must be a valid JSON encoded path")
+	}
+
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return json.Marshal(v)
+	}
+
+	kvs := make([]KV, 0, len(m))
+	for k, v := range m {
+		kvs = append(kvs, KV{Key: k, Value: v})
+	}
+
+	sortChildren(kvs, child)
+
+	// filters compare the value each child is ordered by with their bound
+	filter := func(param string, keep func(c int) bool) error {
+		s, ok := params[param]
+		if !ok {
+			return nil
+		}
+
+		var bound interface{}
+		if err := json.Unmarshal([]byte(s), &bound); err != nil {
+			return badRequest(param + " must be a valid JSON value")
+		}
+
+		ret := kvs[:0]
+		for _, kv := range kvs {
+			var c int
+			if child == "$key" {
+				k, _ := bound.(string)
+				c = compareKeys(kv.Key, k)
+			} else {
+				c = compareValues(orderValue(child, kv), bound)
+			}
+
+			if keep(c) {
+				ret = append(ret, kv)
+			}
+		}
+		kvs = ret
+
+		return nil
+	}
+
+	for _, err := range []error{
+		filter("startAt", func(c int) bool { return c >= 0 }),
+		filter("endAt", func(c int) bool { return c <= 0 }),
+		filter("equalTo", func(c int) bool { return c == 0 }),
+	} {
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if s, ok := params["limitToFirst"]; ok {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 0 {
+			return nil, badRequest("limitToFirst must be a positive integer")
+		}
+		if n < len(kvs) {
+			kvs = kvs[:n]
+		}
+	}
+
+	if s, ok := params["limitToLast"]; ok {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 0 {
+			return nil, badRequest("limitToLast must be a positive integer")
+		}
+		if n < len(kvs) {
+			kvs = kvs[len(kvs)-n:]
+		}
+	}
+
+	var buf bytes.Buffer
+
+	buf.WriteByte('{')
+	for i, kv := range kvs {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+
+		k, _ := json.Marshal(kv.Key)
+		v, err := json.Marshal(kv.Value)
+		if err != nil {
+			return nil, err
+		}
+
+		buf.Write(k)
+		buf.WriteByte(':')
+		buf.Write(v)
+	}
+	buf.WriteByte('}')
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,107 @@
+package firebase
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestFake(t *testing.T) {
+	fake := NewFake()
+
+	client := new(F)
+	client.Init("https://example.firebaseio.com", "", fake)
+
+	if err := fake.Seed("users/jack", Name{First: "Jack", Last: "Sparrow"}); err != nil {
+		t.Fatalf("%v\n", err)
+	}
+
+	if err := client.Update("users/jack", map[string]string{"Last": "Black"}, nil); err != nil {
+		t.Fatalf("%v\n", err)
+	}
+
+	var name Name
+	if err := client.Get("users/jack", nil, &name); err != nil || name != (Name{"Jack", "Black"}) {
+		t.Fatalf("Expected the update to be merged, got %+v and %v\n", name, err)
+	}
+
+	if _, err := client.Set("users/will", Name{First: "Will"}, nil); err != nil {
+		t.Fatalf("%v\n", err)
+	}
+
+	if err := client.Remove("users/jack", nil); err != nil {
+		t.Fatalf("%v\n", err)
+	}
+
+	keys, err := client.Keys("users")
+	if err != nil || !reflect.DeepEqual(keys, []string{"will"}) {
+		t.Fatalf("Unexpected keys %v and error %v\n", keys, err)
+	}
+
+	first, _ := client.Ref("messages").Push("a", nil)
+	second, _ := client.Ref("messages").Push("b", nil)
+
+	if first.Key() >= second.Key() || fake.Data("messages/"+second.Key()) != "b" {
+		t.Fatalf("Expected sortable push keys, got %s and %s\n", first.Key(), second.Key())
+	}
+}
+
+func TestFakeQuery(t *testing.T) {
+	fake := NewFake()
+	fake.Seed("ages", map[string]interface{}{
+		"amy": map[string]interface{}{"age": 20},
+		"bob": map[string]interface{}{"age": 40},
+		"cat": map[string]interface{}{"age": 30},
+		"dan": map[string]interface{}{"age": 10},
+		"eve": map[string]interface{}{},
+	})
+
+	client := new(F)
+	client.Init("https://example.firebaseio.com", "", fake)
+
+	tests := []struct {
+		q        *Query
+		expected []string
+	}{
+		{NewQuery().OrderByChild("age"), []string{"eve", "dan", "amy", "cat", "bob"}},
+		{NewQuery().OrderByChild("age").StartAt(20).LimitToFirst(2), []string{"amy", "cat"}},
+		{NewQuery().OrderByChild("age").EndAt(30).LimitToLast(2), []string{"amy", "cat"}},
+		{NewQuery().OrderByChild("age").EqualTo(40), []string{"bob"}},
+		{NewQuery().OrderByKey().StartAt("c"), []string{"cat", "dan", "eve"}},
+	}
+
+	for _, test := range tests {
+		kvs, err := client.OrderedChildren("ages", test.q)
+		if err != nil {
+			t.Fatalf("%v\n", err)
+		}
+
+		var keys []string
+		for _, kv := range kvs {
+			keys = append(keys, kv.Key)
+		}
+
+		if !reflect.DeepEqual(keys, test.expected) {
+			t.Errorf("Expected %v for %v, got %v\n", test.expected, test.q.Params(), keys)
+		}
+	}
+
+	var apiErr *APIError
+	if _, err := client.OrderedChildren("ages", NewQuery().LimitToFirst(1)); !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadRequest {
+		t.Fatalf("Expected a 400 without orderBy, got %v\n", err)
+	}
+}
+
+func TestCompareKeys(t *testing.T) {
+	keys := []string{"b", "10", "a", "2", "-1"}
+
+	sort.Slice(keys, func(i, j int) bool {
+		return compareKeys(keys[i], keys[j]) < 0
+	})
+
+	if expected := []string{"-1", "2", "10", "a", "b"}; !reflect.DeepEqual(keys, expected) {
+		t.Fatalf("Expected %v, got %v\n", expected, keys)
+	}
+}
@@ -2,14 +2,23 @@
 package firebase
 
 import (
+	"bufio"
 	"bytes"
+	"compress/gzip"
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
 	"io/ioutil"
-	"log"
 	"net/http"
 	"net/url"
+	"os"
+	"reflect"
+	"sort"
 	"strings"
+	"sync"
+	"time"
 )
 
 // Api is the interface for interacting with Firebase.
@@ -18,187 +27,1311 @@ type Api interface {
 	Call(method, path, auth string, body []byte, params map[string]string) ([]byte, error)
 }
 
+// ContextApi is implemented by Api implementations that support cancellation.
+// When the Api given to Init also implements ContextApi, the context-aware
+// methods of F use CallContext so that in-flight requests can be aborted.
+type ContextApi interface {
+	Api
+	CallContext(ctx context.Context, method, path, auth string, body []byte, params map[string]string) ([]byte, error)
+}
+
 // F is the Firebase client.
+//
+// The methods of F are safe for concurrent use by multiple goroutines; the
+// exported fields configure the client and should be set before it is shared.
+// Methods navigating the tree, such as Child, return new references rather
+// than modifying the receiver.
 type F struct {
 	// Url is the client's base URL used for all calls.
 	Url string
 
 	// Auth is authentication token used when making calls.
 	// The token is optional and can also be overwritten on an individual
-	// call basis via params.
+	// call basis via params. Use SetAuth to change it once the client is
+	// in use.
 	Auth string
 
+	// TokenSource provides the authentication token before each call when
+	// set, taking precedence over Auth. Tokens are sent like Auth.
+	TokenSource TokenSource
+
+	// HTTPClient is the HTTP client used to make calls when no custom Api
+	// was given to Init. It can be replaced to configure timeouts, proxies or
+	// a custom Transport. When nil, http.DefaultClient is used.
+	HTTPClient *http.Client
+
+	// Timeout bounds the duration of every call, after which the request is
+	// aborted. Init sets it to DefaultTimeout unless it was already set; zero
+	// means no timeout, which is useful when deadlines are managed through
+	// contexts instead, and must then be set after Init.
+	Timeout time.Duration
+
+	// Retry configures how calls failing with a network error or a 5xx
+	// response are retried. Only idempotent methods are retried, so Push is
+	// never retried. When nil, failed calls are not retried.
+	Retry *RetryPolicy
+
+	// Logger receives the log messages of the client, with credentials
+	// redacted from URLs. When nil, nothing is logged.
+	Logger Logger
+
+	// CloseConnections closes the connection after each call instead of
+	// keeping it alive to be reused by the next calls, which saves a TCP and
+	// TLS handshake per call. It can help with proxies or load balancers
+	// that do not cope with long-lived connections.
+	CloseConnections bool
+
+	// CompressRequests gzip-compresses request bodies of at least this many
+	// bytes, sending them with a "Content-Encoding: gzip" header, which saves
+	// upload bandwidth for large writes. Zero disables compression. Firebase
+	// does not document support for compressed request bodies, so make sure
+	// the database, or the emulator, accepts them before enabling it. It is
+	// only used by the built-in client.
+	CompressRequests int
+
+	// MethodOverride sends PUT, PATCH and DELETE requests as POST requests
+	// with an X-HTTP-Method-Override header, which Firebase honors, for
+	// networks whose proxies or firewalls only allow GET and POST. Logs,
+	// hooks and retries still see the original method. It is only used by
+	// the built-in client.
+	MethodOverride bool
+
+	// DisableGzip stops the client from asking for gzip-compressed
+	// responses, e.g. when behind a proxy that mangles encodings.
+	DisableGzip bool
+
+	// BearerAuth sends the credential in an "Authorization: Bearer" header
+	// instead of the query string, where it could leak into server and proxy
+	// logs. This is recommended for OAuth2 access tokens, while ID tokens and
+	// legacy database secrets must be sent in the query string; references
+	// returned by AsUser always do so.
+	BearerAuth bool
+
+	// Cache stores the values read by CachedChild along with their ETags.
+	// When nil, CachedChild does not cache anything.
+	Cache *Cache
+
+	// EmulatorHost routes calls to the Realtime Database emulator listening
+	// on the given host and port, such as "localhost:9000". Calls are then
+	// made over plain HTTP with the ns parameter set to the database name,
+	// and authenticated as the emulator owner instead of with Auth or
+	// TokenSource, except for the ID tokens of AsUser.
+	// Init sets it from the FIREBASE_DATABASE_EMULATOR_HOST environment
+	// variable.
+	EmulatorHost string
+
+	// StreamDecode makes Child decode responses as they are read rather
+	// than buffering them first, which roughly halves the memory needed for
+	// large values. It is only used by the built-in client without
+	// middlewares, see Use, and such reads are not retried.
+	StreamDecode bool
+
+	// Concurrency bounds the number of requests made at once by GetMulti.
+	// When zero, DefaultConcurrency is used.
+	Concurrency int
+
+	// OnAuthError is called when a call fails with an error matching
+	// ErrUnauthorized, such as when the token expired. If it returns true,
+	// e.g. after refreshing the token with SetAuth, the call is made once
+	// more with the current credential. Streams are not reissued.
+	OnAuthError func(err error) bool
+
+	// OnRequest is called before each HTTP request made by the built-in
+	// client, including retries, with the method and the URL, from which
+	// credentials are redacted. It can be used to collect metrics.
+	OnRequest func(method, path string)
+
+	// OnResponse is called after each HTTP request made by the built-in
+	// client with the method, the redacted URL, the status code, or zero
+	// if no response was received, the time taken until the response
+	// headers were received and the error, if any.
+	OnResponse func(method, path string, status int, dur time.Duration, err error)
+
+	// RateLimiter delays the HTTP requests made by the built-in client,
+	// including retries and streams, to stay under the given rate. Calls
+	// fail with ErrRateLimited when they would have to wait past their
+	// deadline. When nil, requests are not limited.
+	RateLimiter *RateLimiter
+
+	// Tracer creates a span for each HTTP request made by the built-in
+	// client, as a child of the span carried by the context of the call.
+	// When nil, requests are not traced.
+	Tracer Tracer
+
+	// MaxResponseBytes caps the size of the decompressed responses read by
+	// the built-in client, so that an unexpectedly large value cannot exhaust
+	// memory. Larger responses fail with ErrResponseTooLarge. Zero means no
+	// limit. Streams opened by Watch are not limited.
+	MaxResponseBytes int64
+
+	// UseNumber decodes the numbers read into interface{} values as
+	// json.Number instead of float64, which cannot represent integers above
+	// 2^53 exactly, such as large IDs. Use Int64 and Float64 to convert them.
+	UseNumber bool
+
+	// StrictKeys checks the values written by Set, Push, Update and the
+	// other write methods with Validate before sending them, as well as the
+	// keys of the paths of all calls, so that keys Firebase would reject
+	// fail early with a descriptive error.
+	StrictKeys bool
+
+	// UserAgent is sent in the User-Agent header of requests, which helps
+	// attribute traffic in Firebase and proxy logs. When empty, a custom
+	// User-Agent in Header is sent, or DefaultUserAgent otherwise. It is only
+	// used by the built-in client.
+	UserAgent string
+
+	// Header holds additional headers sent with every call, such as a
+	// client version or a correlation ID. Headers set by the client itself,
+	// like Authorization or Accept, take precedence. Headers are only sent by
+	// the built-in client.
+	Header http.Header
+
 	// api is the underlying client used to make calls.
+	// When nil, the built-in HTTP implementation is used.
 	api Api
 
+	// mu guards value and Auth, see SetAuth
+	mu sync.RWMutex
+
 	// value is the value of the object at the current Url
 	value interface{}
+
+	// loaded is set once value was looked up, even if it is null
+	loaded bool
+
+	// null is set when the value looked up was null
+	null bool
+
+	// root is the base URL given to Init
+	root string
+
+	// idToken is set when Auth is the ID token of a user, see AsUser
+	idToken bool
+
+	// watchers are the streams opened by Watch, stopped by Close
+	watchers *watchers
+
+	// middlewares wrap the Api of the calls made with call, see Use
+	middlewares []Middleware
+
+	// params are sent with every call, see WithParam
+	params map[string]string
 }
 
-// struct is the internal implementation of the Firebase API client.
-type client struct{}
+// client is the internal implementation of the Firebase API client.
+type client struct {
+	// httpClient is the HTTP client used to make calls to Firebase
+	httpClient *http.Client
+
+	// retryPolicy configures retries of failed calls, if any
+	retryPolicy *RetryPolicy
+
+	// logger receives the log messages, if any
+	logger Logger
+
+	// disableGzip stops asking for compressed responses
+	disableGzip bool
+
+	// closeConnections disables keep-alive
+	closeConnections bool
+
+	// methodOverride tunnels the methods other than GET and POST through POST
+	methodOverride bool
+
+	// compressRequests is the size from which request bodies are gzipped,
+	// if positive
+	compressRequests int
+
+	// bearerAuth sends auth in a header rather than the query string
+	bearerAuth bool
+
+	// emulatorHost is the host of the emulator to route calls to, if any
+	emulatorHost string
+
+	// idToken is set when auth is the ID token of a user, which is always
+	// sent in the query string, even to the emulator
+	idToken bool
+
+	// header holds the headers sent with every call
+	header http.Header
+
+	// onRequest and onResponse are the hooks called around each request
+	onRequest  func(method, path string)
+	onResponse func(method, path string, status int, dur time.Duration, err error)
+
+	// tracer traces each request, if set
+	tracer Tracer
+
+	// rateLimiter limits the rate of requests, if set
+	rateLimiter *RateLimiter
+
+	// maxResponseBytes caps the size of responses, if positive
+	maxResponseBytes int64
+
+	// userAgent overrides the User-Agent header, if set
+	userAgent string
+}
 
 // suffix is the Firebase suffix for invoking their API via HTTP
 const suffix = ".json"
 
-// httpClient is the HTTP client used to make calls to Firebase
-var httpClient = new(http.Client)
+// Version is the version of this package, sent in DefaultUserAgent.
+const Version = "0.1.0"
+
+// DefaultUserAgent is the User-Agent header sent unless UserAgent is set.
+const DefaultUserAgent = "campoy-firebase-go/" + Version
+
+// EmulatorHostEnv is the environment variable read by Init to set
+// EmulatorHost.
+const EmulatorHostEnv = "FIREBASE_DATABASE_EMULATOR_HOST"
+
+// DefaultTimeout is the per-call timeout set by Init when none was set.
+const DefaultTimeout = 30 * time.Second
 
 // Init initializes the Firebase client with a given root url and optional auth token.
 // The initialization can also pass a mock api for testing purposes.
-func (f *F) Init(root, auth string, api Api) {
-	if api == nil {
-		api = new(client)
+// Unless HTTPClient was already set, each initialized client gets its own
+// http.Client so that settings are not shared between unrelated clients.
+// An error is returned if root is not an absolute URL with a scheme and host.
+// NewClient is preferred for new code.
+func (f *F) Init(root, auth string, api Api) error {
+	u, err := url.Parse(root)
+	if err != nil {
+		return fmt.Errorf("firebase: invalid root url %q: %w", root, err)
+	}
+
+	if u.Scheme == "" || u.Host == "" {
+		return fmt.Errorf("firebase: invalid root url %q: missing scheme or host", root)
+	}
+
+	root = strings.TrimRight(root, "/")
+
+	if f.HTTPClient == nil {
+		f.HTTPClient = new(http.Client)
 	}
 
 	f.api = api
 	f.Url = root
+	f.root = root
 	f.Auth = auth
+	if f.Timeout == 0 {
+		f.Timeout = DefaultTimeout
+	}
+
+	if f.watchers == nil {
+		f.watchers = new(watchers)
+	}
+
+	if host := os.Getenv(EmulatorHostEnv); host != "" && f.EmulatorHost == "" {
+		f.EmulatorHost = host
+	}
+
+	return nil
+}
+
+// Close stops the streams opened by Watch with f or the references derived
+// from it, and closes the idle connections of HTTPClient, e.g. before
+// discarding a client. Closing the idle connections of a client sharing its
+// Transport with others, like http.DefaultTransport, only makes the other
+// clients reconnect. Calls can still be made after Close.
+func (f *F) Close() error {
+	f.watchers.stop()
+
+	if f.HTTPClient != nil {
+		f.HTTPClient.CloseIdleConnections()
+	}
+
+	return nil
 }
 
 // Value returns the value of of the current Url.
+// The value is looked up on first use and cached afterwards, until the
+// current Url is updated through Update.
 func (f *F) Value() interface{} {
+	v, _, _ := f.lookup(context.Background())
+
+	return v
+}
+
+// ValueContext is like Value but uses ctx for the lookup, if one is needed,
+// and returns the error of a failed lookup. A null value is returned as nil
+// with no error.
+func (f *F) ValueContext(ctx context.Context) (interface{}, error) {
+	v, _, err := f.lookup(ctx)
+
+	return v, err
+}
+
+// RawValue returns the value of the current Url as bytes, looking it up
+// like Value if needed. Strings, such as base64-encoded blobs stored at a
+// leaf, are returned as is rather than as quoted JSON, and other values are
+// returned JSON-encoded. It returns nil for a null value or when the lookup
+// fails. Firebase has no raw download mode, so the value is still
+// transferred as JSON.
+func (f *F) RawValue() []byte {
+	v, ok, _ := f.lookup(context.Background())
+	if !ok {
+		return nil
+	}
+
+	switch v := v.(type) {
+	case string:
+		return []byte(v)
+	case *string:
+		return []byte(*v)
+	}
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+
+	return b
+}
+
+// HasValue reports whether there is data at the current Url, i.e. whether
+// Firebase returned something other than null. This also holds when the
+// value was decoded into a pointer given to Child, which is never nil.
+// The value is looked up like Value if needed; HasValue returns false when
+// the lookup fails.
+func (f *F) HasValue() bool {
+	_, ok, _ := f.lookup(context.Background())
+
+	return ok
+}
+
+// lookup returns the value of the current Url and whether it is not null,
+// looking it up if needed.
+func (f *F) lookup(ctx context.Context) (interface{}, bool, error) {
+	f.mu.RLock()
+	v, loaded, null := f.value, f.loaded, f.null
+	f.mu.RUnlock()
+
+	if loaded {
+		return v, !null, nil
+	}
+
 	// if we have not yet performed a look-up, do it so a value is returned
-	if f.value == nil {
-		var v interface{}
-		f = f.Child("", nil, v)
+	// and cache it for subsequent calls
+	ret, err := f.child(ctx, "", nil, nil)
+	if err != nil {
+		return nil, false, err
 	}
 
-	if f == nil {
+	f.mu.Lock()
+	f.setValue(ret.value, ret.null)
+	f.mu.Unlock()
+
+	return ret.value, !ret.null, nil
+}
+
+// setValue records the value looked up or written at the current Url, and
+// whether it is null.
+func (f *F) setValue(v interface{}, null bool) {
+	f.value, f.loaded, f.null = v, true, null
+}
+
+// Key returns the last segment of the current Url, such as the key
+// generated by Push, or an empty string for the root of the database.
+func (f *F) Key() string {
+	_, p, ok := f.split()
+	if !ok {
+		return ""
+	}
+
+	key, err := url.PathUnescape(p[strings.LastIndex(p, "/")+1:])
+	if err != nil {
+		return ""
+	}
+
+	return key
+}
+
+// Parent returns a reference to the parent of the current Url, or nil for
+// the root of the database.
+func (f *F) Parent() *F {
+	base, p, ok := f.split()
+	if !ok || p == "" {
 		return nil
 	}
 
-	return f.value
+	if i := strings.LastIndex(p, "/"); i >= 0 {
+		return f.derive(base + "/" + p[:i])
+	}
+
+	return f.derive(base)
+}
+
+// Root returns a reference to the base Url given to Init.
+func (f *F) Root() *F {
+	if f.root == "" {
+		return f.derive(f.Url)
+	}
+
+	return f.derive(f.root)
+}
+
+// Ref returns a reference to the given path relative to the base Url given
+// to Init, regardless of the current Url. No call is made.
+func (f *F) Ref(path string) *F {
+	return f.derive(f.Root().url(path))
+}
+
+// databaseRoot returns a reference to the root of the database, i.e. the
+// scheme and host of the current Url, whatever the path given to Init.
+func (f *F) databaseRoot() *F {
+	base, _, _ := f.split()
+	return f.derive(base)
+}
+
+// split splits the current Url into the root of the database and the
+// escaped path within it, without leading or trailing slashes.
+func (f *F) split() (string, string, bool) {
+	u, err := url.Parse(f.Url)
+	if err != nil {
+		return "", "", false
+	}
+
+	return u.Scheme + "://" + u.Host, strings.Trim(u.EscapedPath(), "/"), true
 }
 
 // Child returns a populated pointer for a given path.
-// If the path cannot be found, a null pointer is returned.
+// When v is a non-nil pointer, the value is also decoded into it.
+// If the path cannot be found, a null pointer is returned; use ChildE to
+// find out why.
 func (f *F) Child(path string, params map[string]string, v interface{}) *F {
-	u := f.Url + "/" + path
+	return f.ChildContext(context.Background(), path, params, v)
+}
+
+// ChildContext is like Child but uses ctx for the underlying request.
+func (f *F) ChildContext(ctx context.Context, path string, params map[string]string, v interface{}) *F {
+	ret, _ := f.child(ctx, path, params, v)
+
+	return ret
+}
 
-	res, err := f.api.Call("GET", u, f.Auth, nil, params)
+// ChildE is like Child but also returns the reason for a failed lookup.
+// The error includes the requested URL and wraps the underlying HTTP or JSON
+// error. When Firebase responds with 404, the error matches ErrNotFound.
+func (f *F) ChildE(path string, params map[string]string, v interface{}) (*F, error) {
+	return f.child(context.Background(), path, params, v)
+}
+
+// child performs the lookup for the Child family of methods.
+func (f *F) child(ctx context.Context, path string, params map[string]string, v interface{}) (*F, error) {
+	u := f.url(path)
+
+	found, err := f.get(ctx, u, params, &v)
 	if err != nil {
-		return nil
+		return nil, err
+	}
+
+	ret := f.derive(u)
+	ret.setValue(v, !found)
+
+	return ret, nil
+}
+
+// Get reads the value at the given path and decodes it into v, which should
+// be a pointer. Unlike Child, it returns the error of a failed lookup and no
+// reference to the path.
+func (f *F) Get(path string, params map[string]string, v interface{}) error {
+	return f.GetContext(context.Background(), path, params, v)
+}
+
+// GetContext is like Get but uses ctx for the underlying request.
+func (f *F) GetContext(ctx context.Context, path string, params map[string]string, v interface{}) error {
+	_, err := f.get(ctx, f.url(path), params, &v)
+
+	return err
+}
+
+// get reads the value at the given url and decodes it like decode.
+func (f *F) get(ctx context.Context, u string, params map[string]string, v *interface{}) (bool, error) {
+	if _, ok := f.getApi().(*client); ok && f.StreamDecode && len(f.middlewares) == 0 {
+		return f.getStream(ctx, u, params, v)
 	}
 
-	err = json.Unmarshal(res, &v)
+	res, err := f.call(ctx, "GET", u, nil, params)
 	if err != nil {
-		log.Printf("%v\n", err)
-		return nil
+		return false, fmt.Errorf("firebase: get %s: %w", u, err)
+	}
+
+	found, err := decode(res, v, f.UseNumber)
+	if err != nil {
+		return false, &DecodeError{Path: u, Raw: res, Err: err}
 	}
 
-	ret := &F{
-		api:   f.api,
-		Auth:  f.Auth,
-		Url:   u,
-		value: v}
+	return found, nil
+}
+
+// getStream reads the value at the given url for get, decoding it from
+// the response body as it is received.
+func (f *F) getStream(ctx context.Context, u string, params map[string]string, v *interface{}) (bool, error) {
+	ctx, cancel := f.withTimeout(ctx)
+	defer cancel()
 
-	return ret
+	res, err := f.open(ctx, "GET", u, nil, params)
+	if err != nil {
+		return false, fmt.Errorf("firebase: get %s: %w", u, err)
+	}
+	defer res.Body.Close()
+
+	found, err := decodeFrom(res.Body, v, f.UseNumber)
+	if err != nil {
+		return false, &DecodeError{Path: u, Raw: nil, Err: err}
+	}
+
+	return found, nil
+}
+
+// decode unmarshals data into the value pointed to by v. When *v holds a
+// non-nil pointer, such as one passed to Child by the caller, data is decoded
+// into what it points to; otherwise *v is replaced by the decoded value.
+// Numbers decoded into interface{} values are json.Number with useNumber.
+// It also reports whether data is something other than null.
+func decode(data []byte, v *interface{}, useNumber bool) (bool, error) {
+	return decodeFrom(bytes.NewReader(data), v, useNumber)
+}
+
+// decodeFrom is like decode but reads the JSON value from r.
+func decodeFrom(r io.Reader, v *interface{}, useNumber bool) (bool, error) {
+	br := bufio.NewReader(r)
+
+	dec := json.NewDecoder(br)
+	if useNumber {
+		dec.UseNumber()
+	}
+
+	// null is the only JSON value starting with an n
+	found := true
+	if b, err := peek(br); err == nil && b == 'n' {
+		found = false
+	}
+
+	var err error
+	if rv := reflect.ValueOf(*v); rv.Kind() == reflect.Ptr && !rv.IsNil() {
+		err = dec.Decode(*v)
+	} else {
+		err = dec.Decode(v)
+	}
+
+	return found, err
+}
+
+// peek returns the next byte of r that is not JSON whitespace, without
+// consuming it.
+func peek(r *bufio.Reader) (byte, error) {
+	for {
+		b, err := r.Peek(1)
+		if err != nil {
+			return 0, err
+		}
+
+		switch b[0] {
+		case ' ', '\t', '\r', '\n':
+			r.ReadByte()
+		default:
+			return b[0], nil
+		}
+	}
+}
+
+// RawChild returns the raw JSON response for the given path without
+// decoding it.
+func (f *F) RawChild(path string, params map[string]string) ([]byte, error) {
+	u := f.url(path)
+
+	res, err := f.call(context.Background(), "GET", u, nil, params)
+	if err != nil {
+		return nil, fmt.Errorf("firebase: get %s: %w", u, err)
+	}
+
+	return res, nil
+}
+
+// Keys returns the sorted keys of the immediate children at the given path.
+// Only the keys are transferred, which makes it suitable for large nodes.
+func (f *F) Keys(path string) ([]string, error) {
+	var m map[string]bool
+
+	_, err := f.ChildE(path, Shallow().Params(), &m)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys, nil
+}
+
+// Exists reports whether there is data at the given path. It makes a shallow
+// request so that only the keys of the children are transferred, and returns
+// the error of a failed lookup rather than false.
+func (f *F) Exists(path string) (bool, error) {
+	ret, err := f.ChildE(path, Shallow().Params(), nil)
+	if err != nil {
+		return false, err
+	}
+
+	return ret.HasValue(), nil
+}
+
+// Ping checks that Firebase can be reached and that the credentials of f
+// allow reading the root of the database, e.g. for readiness probes. It
+// makes a shallow request so that only the top-level keys are transferred.
+// The returned error matches ErrUnauthorized when access is denied.
+func (f *F) Ping(ctx context.Context) error {
+	root := f.Root()
+
+	_, err := root.call(ctx, "GET", root.Url, nil, Shallow().Params())
+	if err != nil {
+		return fmt.Errorf("firebase: ping %s: %w", root.Url, err)
+	}
+
+	return nil
 }
 
 // Push creates a new value under the current root url.
-// A populated pointer with that value is also returned.
+// A populated pointer with that value is also returned, whose Key is the
+// key generated by Firebase.
+// Push relies on the generated name in the response, so it cannot be used
+// with print=silent and returns ErrSilentPush if asked to.
 func (f *F) Push(value interface{}, params map[string]string) (*F, error) {
-	body, err := json.Marshal(value)
+	return f.PushContext(context.Background(), value, params)
+}
+
+// PushContext is like Push but uses ctx for the underlying request.
+func (f *F) PushContext(ctx context.Context, value interface{}, params map[string]string) (*F, error) {
+	res, err := f.AddContext(ctx, value, params)
+	return res.Ref, err
+}
+
+// PushResult is the outcome of Add: the key generated by Firebase and a
+// reference to the new child, which holds the pushed value.
+type PushResult struct {
+	Key string
+	Ref *F
+}
+
+// Add is like Push but also returns the generated key, which would otherwise
+// have to be read from the Url of the returned reference.
+func (f *F) Add(value interface{}, params map[string]string) (PushResult, error) {
+	return f.AddContext(context.Background(), value, params)
+}
+
+// AddContext is like Add but uses ctx for the underlying request.
+func (f *F) AddContext(ctx context.Context, value interface{}, params map[string]string) (PushResult, error) {
+	if params["print"] == "silent" {
+		return PushResult{}, ErrSilentPush
+	}
+
+	body, err := f.marshal(f.Url, value, false)
+	if err != nil {
+		return PushResult{}, err
+	}
+
+	key, err := f.post(ctx, body, params)
 	if err != nil {
-		log.Printf("%v\n", err)
+		return PushResult{}, err
+	}
+
+	ret := f.derive(f.url(key))
+	ret.setValue(value, value == nil)
+
+	return PushResult{Key: key, Ref: ret}, nil
+}
+
+// PushRaw is like Push but sends the given JSON as is, without marshaling
+// it, e.g. when copying data read from another store. It returns an error
+// without calling Firebase if data is not valid JSON. Unlike Push, the
+// value of the returned reference is looked up when first needed.
+func (f *F) PushRaw(data []byte, params map[string]string) (*F, error) {
+	if params["print"] == "silent" {
+		return nil, ErrSilentPush
+	}
+
+	if err := f.checkRaw(f.Url, data, false); err != nil {
 		return nil, err
 	}
 
-	res, err := f.api.Call("POST", f.Url, f.Auth, body, params)
+	key, err := f.post(context.Background(), data, params)
 	if err != nil {
 		return nil, err
 	}
 
-	var r map[string]string
+	return f.derive(f.url(key)), nil
+}
+
+// post pushes body under the current Url and returns the generated key,
+// which Firebase sends as {"name": key}.
+func (f *F) post(ctx context.Context, body []byte, params map[string]string) (string, error) {
+	res, err := f.call(ctx, "POST", f.Url, body, params)
+	if err != nil {
+		return "", err
+	}
+
+	if len(bytes.TrimSpace(res)) == 0 {
+		return "", fmt.Errorf("firebase: push %s: empty response, expected the generated key", f.Url)
+	}
+
+	var r struct {
+		Name string `json:"name"`
+	}
 
 	err = json.Unmarshal(res, &r)
 	if err != nil {
-		log.Printf("%v\n", err)
-		return nil, err
+		return "", &DecodeError{Path: f.Url, Raw: res, Err: err}
 	}
 
-	ret := &F{
-		api:   f.api,
-		Auth:  f.Auth,
-		Url:   f.Url + "/" + r["name"],
-		value: value}
+	if r.Name == "" {
+		return "", fmt.Errorf("firebase: push %s: no generated key in response %q", f.Url, res)
+	}
 
-	return ret, nil
+	return r.Name, nil
 }
 
 // Set overwrites the value at the specified path and returns populated pointer
 // for the updated path.
 func (f *F) Set(path string, value interface{}, params map[string]string) (*F, error) {
-	u := f.Url + "/" + path
+	return f.SetContext(context.Background(), path, value, params)
+}
 
-	body, err := json.Marshal(value)
+// SetContext is like Set but uses ctx for the underlying request.
+func (f *F) SetContext(ctx context.Context, path string, value interface{}, params map[string]string) (*F, error) {
+	u := f.url(path)
+
+	body, err := f.marshal(u, value, false)
 	if err != nil {
-		log.Printf("%v\n", err)
 		return nil, err
 	}
 
-	res, err := f.api.Call("PUT", u, f.Auth, body, params)
+	res, err := f.call(ctx, "PUT", u, body, params)
 
 	if err != nil {
 		return nil, err
 	}
 
-	ret := &F{
-		api:  f.api,
-		Auth: f.Auth,
-		Url:  u}
+	ret := f.derive(u)
 
-	if len(res) > 0 {
+	// with print=silent there is no response body to decode, so keep the
+	// value that was just written instead
+	if len(res) == 0 || params["print"] == "silent" {
+		ret.setValue(value, value == nil)
+	} else {
 		var r interface{}
 
-		err = json.Unmarshal(res, &r)
+		_, err = decode(res, &r, f.UseNumber)
 		if err != nil {
-			log.Printf("%v\n", err)
-			return nil, err
+			return nil, &DecodeError{Path: u, Raw: res, Err: err}
 		}
 
-		ret.value = r
+		ret.setValue(r, r == nil)
 	}
 
 	return ret, nil
 }
 
+// SetRaw is like Set but sends the given JSON as is, without marshaling it,
+// which keeps its formatting and avoids decoding data read from another
+// store. It returns an error without calling Firebase if data is not valid
+// JSON. Since nothing is returned, Firebase is asked not to echo the data.
+func (f *F) SetRaw(path string, data []byte, params map[string]string) error {
+	u := f.url(path)
+
+	if err := f.checkRaw(u, data, false); err != nil {
+		return err
+	}
+
+	_, err := f.call(context.Background(), "PUT", u, data, withParam(params, "print", "silent"))
+	return err
+}
+
+// SetRoot overwrites the data at the base Url given to Init with value and
+// returns a reference to it holding the written value. That base Url is the
+// root of the database only if Init was given no path; otherwise only the
+// subtree at that path is replaced. All existing data there not in value is
+// deleted, which is why this is not done by Set with an empty path, which
+// only overwrites the current Url. The cached value of f is cleared, since
+// it no longer reflects the data.
+func (f *F) SetRoot(value interface{}, params map[string]string) (*F, error) {
+	ret, err := f.Root().Set("", value, params)
+
+	f.mu.Lock()
+	f.value, f.loaded = nil, false
+	f.mu.Unlock()
+
+	return ret, err
+}
+
+// SetSilent is like Set but asks Firebase not to echo back the written data,
+// which saves bandwidth for high-throughput writes. The returned pointer holds
+// the value that was written.
+func (f *F) SetSilent(path string, value interface{}, params map[string]string) (*F, error) {
+	return f.Set(path, value, withParam(params, "print", "silent"))
+}
+
 // Update performs a partial update with the given value at the specified path.
 func (f *F) Update(path string, value interface{}, params map[string]string) error {
-	body, err := json.Marshal(value)
+	return f.UpdateContext(context.Background(), path, value, params)
+}
+
+// UpdateContext is like Update but uses ctx for the underlying request.
+func (f *F) UpdateContext(ctx context.Context, path string, value interface{}, params map[string]string) error {
+	body, err := f.marshal(f.url(path), value, true)
 	if err != nil {
-		log.Printf("%v\n", err)
 		return err
 	}
 
-	_, err = f.api.Call("PATCH", f.Url+"/"+path, f.Auth, body, params)
+	_, err = f.call(ctx, "PATCH", f.url(path), body, params)
 
 	// if we've just updated the root node, clear the value so it gets looked up
 	// again and populated correctly since we just applied a diffgram
 	if len(path) == 0 {
-		f.value = nil
+		f.mu.Lock()
+		f.value, f.loaded = nil, false
+		f.mu.Unlock()
 	}
 
 	return err
 }
 
+// UpdateChildren atomically applies a multi-location update to the current
+// node. Keys are paths relative to the current node, such as "users/1/name",
+// and may not go up the tree with "..".
+func (f *F) UpdateChildren(updates map[string]interface{}, params map[string]string) error {
+	for k := range updates {
+		if strings.Trim(k, "/") == "" {
+			return fmt.Errorf("firebase: invalid update path %q: empty path", k)
+		}
+
+		for _, s := range strings.Split(k, "/") {
+			if s == ".." {
+				return fmt.Errorf("firebase: invalid update path %q: paths must be relative to %s", k, f.Url)
+			}
+		}
+	}
+
+	return f.UpdateContext(context.Background(), "", updates, params)
+}
+
 // Remove deletes the data at the given path.
 func (f *F) Remove(path string, params map[string]string) error {
-	_, err := f.api.Call("DELETE", f.Url+"/"+path, f.Auth, nil, params)
+	return f.RemoveContext(context.Background(), path, params)
+}
+
+// RemoveContext is like Remove but uses ctx for the underlying request.
+func (f *F) RemoveContext(ctx context.Context, path string, params map[string]string) error {
+	_, err := f.call(ctx, "DELETE", f.url(path), nil, params)
+
+	return err
+}
+
+// url returns the URL of the given path relative to f.
+// Leading, trailing and repeated slashes are ignored, so the empty path
+// refers to f itself. Each segment of the path is escaped, so keys can contain
+// any character except slashes, which separate segments.
+func (f *F) url(path string) string {
+	u := strings.TrimRight(f.Url, "/")
+
+	for _, s := range strings.Split(path, "/") {
+		if s != "" {
+			u += "/" + url.PathEscape(s)
+		}
+	}
+
+	return u
+}
+
+// WithParam returns a reference to the current Url that sends the given
+// query parameter with every call, in addition to the params of each call,
+// which take precedence. The receiver is not modified, so calls can be
+// chained to accumulate parameters:
+//
+//	f.WithParam("orderBy", `"name"`).WithParam("limitToFirst", "10").Child("users", nil, &v)
+func (f *F) WithParam(key, value string) *F {
+	ret := f.Clone()
+	ret.params = withParam(f.params, key, value)
+
+	return ret
+}
+
+// WithHeader returns a reference to the current Url that sends the given
+// header with every call, in addition to Header. The receiver is not
+// modified, so calls can be chained like WithParam.
+func (f *F) WithHeader(key, value string) *F {
+	ret := f.Clone()
+	if ret.Header == nil {
+		ret.Header = http.Header{}
+	}
+	ret.Header.Set(key, value)
+
+	return ret
+}
+
+// Clone returns a copy of f, with the same Url, configuration and parameters
+// set with WithParam but without its cached value, which can be modified
+// without affecting f. Header is copied as well. The Api, HTTPClient,
+// TokenSource, Cache and other shared components are not: copies share them
+// on purpose, so that they reuse the same connections, tokens and cache.
+func (f *F) Clone() *F {
+	ret := f.derive(f.Url)
+	ret.params = f.params
+	ret.Header = f.Header.Clone()
+
+	return ret
+}
+
+// withParams returns params merged over the parameters set with WithParam.
+func (f *F) withParams(params map[string]string) map[string]string {
+	if len(f.params) == 0 {
+		return params
+	}
+
+	ret := make(map[string]string, len(f.params)+len(params))
+	for k, v := range f.params {
+		ret[k] = v
+	}
+	for k, v := range params {
+		ret[k] = v
+	}
+
+	return ret
+}
+
+// withParam returns a copy of params with the given parameter set.
+func withParam(params map[string]string, key, value string) map[string]string {
+	ret := make(map[string]string, len(params)+1)
+	for k, v := range params {
+		ret[k] = v
+	}
+	ret[key] = value
+
+	return ret
+}
+
+// derive returns a new reference at the given url sharing the configuration of f.
+func (f *F) derive(u string) *F {
+	return &F{
+		api:              f.api,
+		Auth:             f.auth(),
+		HTTPClient:       f.HTTPClient,
+		Timeout:          f.Timeout,
+		Retry:            f.Retry,
+		Logger:           f.Logger,
+		DisableGzip:      f.DisableGzip,
+		CloseConnections: f.CloseConnections,
+		MethodOverride:   f.MethodOverride,
+		CompressRequests: f.CompressRequests,
+		BearerAuth:       f.BearerAuth,
+		Cache:            f.Cache,
+		Header:           f.Header,
+		UserAgent:        f.UserAgent,
+		StreamDecode:     f.StreamDecode,
+		OnAuthError:      f.OnAuthError,
+		OnRequest:        f.OnRequest,
+		OnResponse:       f.OnResponse,
+		Tracer:           f.Tracer,
+		RateLimiter:      f.RateLimiter,
+		Concurrency:      f.Concurrency,
+		UseNumber:        f.UseNumber,
+		StrictKeys:       f.StrictKeys,
+		MaxResponseBytes: f.MaxResponseBytes,
+		EmulatorHost:     f.EmulatorHost,
+		Url:              u,
+		root:             f.root,
+		idToken:          f.idToken,
+		watchers:         f.watchers,
+		middlewares:      f.middlewares,
+		TokenSource:      f.TokenSource}
+}
+
+// Api returns the Api used for calls: the one given to Init or SetApi, or
+// else a built-in HTTP client configured from the current fields of f, which
+// later changes to these fields do not affect. It can be wrapped by a custom
+// Api adding logging, metrics or caching, and installed with SetApi:
+//
+//	f.SetApi(&countingApi{next: f.Api()})
+//
+// Wrappers must honor the contract of Call: return the response body of
+// successful calls and the *APIError of error statuses, unchanged, so that
+// errors.Is and retries keep working. They should also implement ContextApi,
+// delegating to CallContext when the wrapped Api does, for calls to remain
+// cancellable.
+func (f *F) Api() Api {
+	return f.getApi()
+}
+
+// SetApi replaces the Api used by f and the references derived from it
+// afterwards. Like with a custom Api given to Init, the methods that need
+// the built-in client, such as Watch or CallFull, then return
+// ErrUnsupported. It should be called before f is shared.
+func (f *F) SetApi(api Api) {
+	f.api = api
+}
+
+// getApi returns the Api used for calls, which is the built-in HTTP client
+// configured from f unless a custom one was given to Init.
+func (f *F) getApi() Api {
+	if f.api != nil {
+		return f.api
+	}
+
+	return &client{
+		httpClient:       f.HTTPClient,
+		retryPolicy:      f.Retry,
+		logger:           f.Logger,
+		disableGzip:      f.DisableGzip,
+		closeConnections: f.CloseConnections,
+		methodOverride:   f.MethodOverride,
+		compressRequests: f.CompressRequests,
+		bearerAuth:       f.BearerAuth,
+		emulatorHost:     f.EmulatorHost,
+		idToken:          f.idToken,
+		header:           f.Header,
+		userAgent:        f.UserAgent,
+		onRequest:        f.OnRequest,
+		onResponse:       f.OnResponse,
+		tracer:           f.Tracer,
+		rateLimiter:      f.RateLimiter,
+		maxResponseBytes: f.MaxResponseBytes}
+}
+
+// withTimeout bounds ctx by the configured Timeout, if any.
+func (f *F) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if f.Timeout > 0 {
+		return context.WithTimeout(ctx, f.Timeout)
+	}
+
+	return ctx, func() {}
+}
+
+// call invokes the api with the given context, falling back to Call for
+// Api implementations that do not support cancellation.
+// The context is bounded by the configured Timeout, if any.
+func (f *F) call(ctx context.Context, method, path string, body []byte, params map[string]string) ([]byte, error) {
+	if err := f.checkPath(path); err != nil {
+		return nil, err
+	}
+
+	api := f.wrap(f.getApi())
+
+	ctx, cancel := f.withTimeout(ctx)
+	defer cancel()
+
+	params = f.withParams(params)
+
+	call := func() ([]byte, error) {
+		auth, err := f.credential()
+		if err != nil {
+			return nil, err
+		}
+
+		return CallContext(ctx, api, method, path, auth, body, params)
+	}
+
+	res, err := call()
+	if f.reauth(err) {
+		return call()
+	}
+
+	return res, err
+}
+
+// CallFull invokes the given HTTP method on the given path relative to the
+// current Url and returns the full response, including its status code and
+// headers. It is only supported by the built-in client.
+func (f *F) CallFull(method, path string, body []byte, params map[string]string) (*Response, error) {
+	return f.callHeader(context.Background(), method, f.url(path), body, params, nil)
+}
+
+// CallStream is like CallFull but returns the response body as it is
+// received instead of buffering it, so that large values can be decoded
+// incrementally, e.g. with json.NewDecoder. The caller must close the body.
+// The request is bounded by Timeout until the body is closed and is not
+// retried. It is only supported by the built-in client.
+func (f *F) CallStream(method, path string, body []byte, params map[string]string) (io.ReadCloser, error) {
+	ctx, cancel := f.withTimeout(context.Background())
+
+	res, err := f.open(ctx, method, f.url(path), bytes.NewReader(body), params)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	return &cancelBody{ReadCloser: res.Body, cancel: cancel}, nil
+}
+
+// cancelBody is a response body releasing the context of its request when
+// closed.
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+// Close closes the body and cancels the request context.
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
 
 	return err
 }
 
+// open sends a single request with the credentials of f and returns the
+// response, whose body must be closed by the caller.
+func (f *F) open(ctx context.Context, method, path string, body io.Reader, params map[string]string) (*http.Response, error) {
+	c, ok := f.getApi().(*client)
+	if !ok {
+		return nil, ErrUnsupported
+	}
+
+	if err := f.checkPath(path); err != nil {
+		return nil, err
+	}
+
+	auth, err := f.credential()
+	if err != nil {
+		return nil, err
+	}
+
+	auth, header := c.bearer(auth, nil)
+	path = c.url(path, auth, f.withParams(params))
+
+	res, _, err := c.open(ctx, method, path, body, header)
+	return res, err
+}
+
+// callHeader is like call but sends additional request headers and returns
+// the full response. Since the Api interface has no notion of headers, it is
+// only supported by the built-in client and returns ErrUnsupported otherwise.
+func (f *F) callHeader(ctx context.Context, method, path string, body []byte, params map[string]string, header http.Header) (*Response, error) {
+	c, ok := f.getApi().(*client)
+	if !ok {
+		return nil, ErrUnsupported
+	}
+
+	if err := f.checkPath(path); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := f.withTimeout(ctx)
+	defer cancel()
+
+	params = f.withParams(params)
+
+	send := func() (*Response, error) {
+		auth, err := f.credential()
+		if err != nil {
+			return nil, err
+		}
+
+		return c.send(ctx, method, path, auth, body, params, header)
+	}
+
+	res, err := send()
+	if f.reauth(err) {
+		return send()
+	}
+
+	return res, err
+}
+
+// Response is the full outcome of a call, for callers that need more than
+// the body, such as the headers for diagnostics.
+type Response struct {
+	// StatusCode is the HTTP status code of the response.
+	StatusCode int
+
+	// Header holds the headers of the response.
+	Header http.Header
+
+	// Body is the decompressed body of the response.
+	Body []byte
+}
+
 // Call invokes the appropriate HTTP method on a given Firebase URL.
 func (c *client) Call(method, path, auth string, body []byte, params map[string]string) ([]byte, error) {
-	if !strings.HasSuffix(path, "/") {
-		path += "/"
+	return c.CallContext(context.Background(), method, path, auth, body, params)
+}
+
+// CallContext is like Call but aborts the request when ctx is done.
+func (c *client) CallContext(ctx context.Context, method, path, auth string, body []byte, params map[string]string) ([]byte, error) {
+	res, err := c.send(ctx, method, path, auth, body, params, nil)
+	if err != nil {
+		return nil, err
 	}
 
-	path += suffix
+	return res.Body, nil
+}
+
+// send is like CallContext but sends additional request headers and returns
+// the full response.
+func (c *client) send(ctx context.Context, method, path, auth string, body []byte, params map[string]string, header http.Header) (*Response, error) {
+	auth, header = c.bearer(auth, header)
+	path = c.url(path, auth, params)
+	body, header = c.compress(body, header)
+
+	return c.retry(ctx, method, func() (*Response, bool, error) {
+		return c.do(ctx, method, path, body, header)
+	})
+}
+
+// bearer moves auth to an Authorization header when bearerAuth is set, and
+// returns the auth to send in the query string along with the headers.
+// With the emulator, the owner credential is always sent instead.
+func (c *client) bearer(auth string, header http.Header) (string, http.Header) {
+	if c.idToken {
+		return auth, header
+	}
+
+	// the emulator grants full access to its owner
+	if c.emulatorHost != "" {
+		auth = "owner"
+	} else if !c.bearerAuth || auth == "" {
+		return auth, header
+	}
+
+	ret := http.Header{"Authorization": {"Bearer " + auth}}
+	for k, v := range header {
+		ret[k] = v
+	}
+
+	return "", ret
+}
+
+// jsonUrl returns the REST endpoint of the given Firebase URL, such as
+// base/users.json, or base/.json for the root of the database.
+// URLs already ending with the suffix are returned unchanged; since keys
+// cannot contain dots, the suffix cannot be part of a key.
+func jsonUrl(u string) string {
+	u = strings.TrimRight(u, "/")
+
+	if strings.HasSuffix(u, suffix) {
+		return u
+	}
+
+	// the root needs a slash between the host and the suffix
+	if i := strings.Index(u, "://"); i >= 0 && !strings.Contains(u[i+3:], "/") {
+		u += "/"
+	}
+
+	return u + suffix
+}
+
+// url returns the full URL to request for the given Firebase URL.
+func (c *client) url(path, auth string, params map[string]string) string {
+	path = jsonUrl(path)
 	qs := url.Values{}
 
+	if c.emulatorHost != "" {
+		path, qs = emulate(path, c.emulatorHost)
+	}
+
 	// if the client has an auth, set it as a query string.
 	// the caller can also override this on a per-call basis
 	// which will happen via params below
@@ -214,33 +1347,283 @@ func (c *client) Call(method, path, auth string, body []byte, params map[string]
 		path += "?" + qs.Encode()
 	}
 
-	req, err := http.NewRequest(method, path, bytes.NewReader(body))
+	return path
+}
+
+// emulate rewrites the given URL to target the emulator at the given host,
+// and returns the ns parameter naming the database for the emulator.
+func emulate(u, host string) (string, url.Values) {
+	p, err := url.Parse(u)
 	if err != nil {
-		log.Printf("Cannot create Firebase request: %v\n", err)
-		return nil, err
+		return u, url.Values{}
+	}
+
+	ns := strings.SplitN(p.Hostname(), ".", 2)[0]
+
+	p.Scheme = "http"
+	p.Host = host
+
+	return p.String(), url.Values{"ns": {ns}}
+}
+
+// setHeader sets the headers of the client and then the given ones on req,
+// along with the User-Agent.
+func (c *client) setHeader(req *http.Request, header http.Header) {
+	req.Header.Set("User-Agent", DefaultUserAgent)
+
+	for k, v := range c.header {
+		req.Header[k] = v
 	}
 
-	req.Close = true
-	log.Printf("Calling %v %q\n", method, path)
+	for k, v := range header {
+		req.Header[k] = v
+	}
 
-	res, err := httpClient.Do(req)
+	if c.userAgent != "" {
+		req.Header.Set("User-Agent", c.userAgent)
+	}
+}
+
+// getHTTPClient returns the HTTP client to use for requests.
+func (c *client) getHTTPClient() *http.Client {
+	if c.httpClient == nil {
+		return http.DefaultClient
+	}
+
+	return c.httpClient
+}
+
+// do performs a single HTTP request and reports whether a failure is
+// transient and the request may be retried.
+func (c *client) do(ctx context.Context, method, path string, body []byte, header http.Header) (*Response, bool, error) {
+	res, retryable, err := c.open(ctx, method, path, bytes.NewReader(body), header)
 	if err != nil {
-		log.Printf("Request to Firebase failed: %v\n", err)
-		return nil, err
+		return nil, retryable, err
 	}
 	defer res.Body.Close()
 
 	ret, err := ioutil.ReadAll(res.Body)
 	if err != nil {
-		log.Printf("Cannot parse Firebase response: %v\n", err)
+		logf(c.logger, "Cannot parse Firebase response: %v\n", err)
+		return nil, !errors.Is(err, ErrResponseTooLarge), err
+	}
+
+	return &Response{
+		StatusCode: res.StatusCode,
+		Header:     res.Header,
+		Body:       ret}, false, nil
+}
+
+// open sends a request and returns the response, whose body is decompressed
+// and must be closed by the caller. Error statuses are returned as *APIError.
+// It also reports whether a failure is transient and may be retried.
+func (c *client) open(ctx context.Context, method, path string, body io.Reader, header http.Header) (*http.Response, bool, error) {
+	if err := c.wait(ctx); err != nil {
+		return nil, false, err
+	}
+
+	if c.onRequest != nil {
+		c.onRequest(method, redact(path))
+	}
+
+	start := time.Now()
+
+	res, retryable, err := c.roundTrip(ctx, method, path, body, header)
+
+	if c.onResponse != nil {
+		c.onResponse(method, redact(path), statusCode(res, err), time.Since(start), err)
+	}
+
+	return res, retryable, err
+}
+
+// wait blocks until the rate limiter, if any, allows a request.
+func (c *client) wait(ctx context.Context) error {
+	if c.rateLimiter == nil {
+		return nil
+	}
+
+	err := c.rateLimiter.Wait(ctx)
+	if err != nil {
+		logf(c.logger, "Firebase request not sent: %v\n", err)
+	}
+
+	return err
+}
+
+// statusCode returns the status code of a response, or of the APIError
+// returned instead, or zero if no response was received.
+func statusCode(res *http.Response, err error) int {
+	var apiErr *APIError
+
+	if res != nil {
+		return res.StatusCode
+	} else if errors.As(err, &apiErr) {
+		return apiErr.StatusCode
+	}
+
+	return 0
+}
+
+// BuildRequest returns the request the built-in client would send to call
+// the given HTTP method on the given Firebase URL, without sending it.
+// It takes the same arguments as Api.Call and is mostly useful to check how
+// a call, such as a query, is encoded. The URL of the request includes auth.
+func BuildRequest(method, path, auth string, body []byte, params map[string]string) (*http.Request, error) {
+	c := new(client)
+
+	return c.newRequest(context.Background(), method, c.url(path, auth, params), bytes.NewReader(body), nil)
+}
+
+// newRequest creates a request to the given URL with the headers of the
+// client and the given ones.
+func (c *client) newRequest(ctx context.Context, method, path string, body io.Reader, header http.Header) (*http.Request, error) {
+	sent := method
+	if c.methodOverride && method != "GET" && method != "POST" {
+		sent = "POST"
+	}
+
+	req, err := http.NewRequestWithContext(ctx, sent, path, body)
+	if err != nil {
 		return nil, err
 	}
 
+	c.setHeader(req, header)
+
+	if sent != method {
+		req.Header.Set("X-HTTP-Method-Override", method)
+	}
+
+	// asking for an encoding explicitly turns off the transparent gzip
+	// support of http.Transport, so the response is decompressed by open
+	if req.Header.Get("Accept-Encoding") == "" {
+		if c.disableGzip {
+			req.Header.Set("Accept-Encoding", "identity")
+		} else {
+			req.Header.Set("Accept-Encoding", "gzip")
+		}
+	}
+
+	req.Close = c.closeConnections
+
+	return req, nil
+}
+
+// roundTrip performs the request for open.
+func (c *client) roundTrip(ctx context.Context, method, path string, body io.Reader, header http.Header) (res *http.Response, retryable bool, err error) {
+	req, err := c.newRequest(ctx, method, path, body, header)
+	if err != nil {
+		err = redactError(err)
+		logf(c.logger, "Cannot create Firebase request: %v\n", err)
+		return nil, false, err
+	}
+
+	if c.tracer != nil {
+		end := c.tracer.StartSpan(req, redact(path))
+		defer func() {
+			end(statusCode(res, err), err)
+		}()
+	}
+
+	logf(c.logger, "Calling %v %q\n", method, redact(path))
+
+	res, err = c.getHTTPClient().Do(req)
+	if err != nil {
+		// surface cancellation and deadlines as such rather than as a
+		// generic network error
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, false, ctxErr
+		}
+
+		err = redactError(err)
+		logf(c.logger, "Request to Firebase failed: %v\n", err)
+		return nil, true, err
+	}
+
+	if res.Header.Get("Content-Encoding") == "gzip" {
+		zr, err := gzip.NewReader(res.Body)
+		if err != nil {
+			res.Body.Close()
+			logf(c.logger, "Cannot decompress Firebase response: %v\n", err)
+			return nil, true, err
+		}
+
+		res.Body = &gzipBody{Reader: zr, body: res.Body}
+	}
+
+	if c.maxResponseBytes > 0 {
+		res.Body = &limitBody{ReadCloser: res.Body, n: c.maxResponseBytes}
+	}
+
 	if res.StatusCode >= 400 {
-		err = errors.New(string(ret))
-		log.Printf("Error encountered from Firebase: %v\n", err)
-		return nil, err
+		defer res.Body.Close()
+
+		ret, _ := ioutil.ReadAll(res.Body)
+		err = newAPIError(res.StatusCode, scrub(string(ret), secrets(req.URL)...))
+		logf(c.logger, "Error encountered from Firebase: %v\n", err)
+		return nil, res.StatusCode >= 500, err
 	}
 
-	return ret, nil
+	return res, false, nil
+}
+
+// limitBody is a response body failing with ErrResponseTooLarge once more
+// than n bytes are read.
+type limitBody struct {
+	io.ReadCloser
+	n int64
+}
+
+// Read reads from the body until the limit is exceeded.
+func (b *limitBody) Read(p []byte) (int, error) {
+	if b.n < 0 {
+		return 0, ErrResponseTooLarge
+	}
+
+	// read one byte past the limit to find out whether it is exceeded
+	if int64(len(p)) > b.n+1 {
+		p = p[:b.n+1]
+	}
+
+	n, err := b.ReadCloser.Read(p)
+	if int64(n) > b.n {
+		n, b.n = int(b.n), -1
+		return n, ErrResponseTooLarge
+	}
+	b.n -= int64(n)
+
+	return n, err
+}
+
+// compress gzips body when it reaches compressRequests bytes, and returns it
+// along with the headers to send.
+func (c *client) compress(body []byte, header http.Header) ([]byte, http.Header) {
+	if c.compressRequests <= 0 || len(body) < c.compressRequests {
+		return body, header
+	}
+
+	var buf bytes.Buffer
+
+	zw := gzip.NewWriter(&buf)
+	zw.Write(body)
+	zw.Close()
+
+	ret := http.Header{"Content-Encoding": {"gzip"}}
+	for k, v := range header {
+		ret[k] = v
+	}
+
+	return buf.Bytes(), ret
+}
+
+// gzipBody decompresses a response body and closes it along with the reader.
+type gzipBody struct {
+	*gzip.Reader
+	body io.Closer
+}
+
+// Close closes the decompressing reader and the underlying body.
+func (b *gzipBody) Close() error {
+	b.Reader.Close()
+	return b.body.Close()
 }
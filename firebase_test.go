@@ -1,7 +1,21 @@
 package firebase
 
 import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
 type Name struct {
@@ -56,6 +70,159 @@ func TestPush(t *testing.T) {
 	}
 }
 
+func TestAdd(t *testing.T) {
+	fake := NewFake()
+
+	client := new(F)
+	client.Init("https://example.firebaseio.com/messages", "", fake)
+
+	res, err := client.Add("hello", nil)
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+
+	if res.Key == "" || res.Ref.Key() != res.Key || res.Ref.Value() != "hello" {
+		t.Fatalf("Unexpected result %+v\n", res)
+	}
+
+	if fake.Data("messages/"+res.Key) != "hello" {
+		t.Fatalf("Expected the value to be pushed under %s\n", res.Key)
+	}
+
+	if _, err := client.Add("hello", map[string]string{"print": "silent"}); err != ErrSilentPush {
+		t.Fatalf("Expected ErrSilentPush, got %v\n", err)
+	}
+}
+
+func TestPushResponse(t *testing.T) {
+	var body string
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(body))
+	}))
+	defer ts.Close()
+
+	client := new(F)
+	client.Init(ts.URL, "", nil)
+
+	body = "{\n  \"name\" : \"-Nabc\"\n}\n"
+
+	r, err := client.Push("a", map[string]string{"print": "pretty"})
+	if err != nil || r.Key() != "-Nabc" {
+		t.Fatalf("Expected a pretty-printed key to be read, got %v\n", err)
+	}
+
+	for _, body = range []string{"", "  \n", "{}", `{"name":""}`, "null"} {
+		if _, err := client.Push("a", nil); err == nil || !strings.Contains(err.Error(), "key") {
+			t.Errorf("Expected a clear error for %q, got %v\n", body, err)
+		}
+	}
+
+	body = "["
+
+	if _, err := client.Push("a", nil); err == nil {
+		t.Fatalf("Expected an error for an invalid response\n")
+	}
+}
+
+func TestRaw(t *testing.T) {
+	var bodies []string
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, r.Method+" "+r.URL.Query().Get("print")+" "+string(b))
+
+		if r.Method == "POST" {
+			w.Write([]byte(`{"name":"-Nabc"}`))
+		}
+	}))
+	defer ts.Close()
+
+	client := new(F)
+	client.Init(ts.URL, "", nil)
+
+	if err := client.SetRaw("users/jack", []byte(`{ "b": 1,  "a": 2 }`), nil); err != nil {
+		t.Fatalf("%v\n", err)
+	}
+
+	r, err := client.Ref("messages").PushRaw([]byte(`"hello"`), nil)
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+
+	if r.Key() != "-Nabc" {
+		t.Fatalf("Unexpected key %s\n", r.Key())
+	}
+
+	expected := []string{`PUT silent { "b": 1,  "a": 2 }`, `POST  "hello"`}
+	if fmt.Sprint(bodies) != fmt.Sprint(expected) {
+		t.Fatalf("Expected the bytes to be sent as is, got %q\n", bodies)
+	}
+
+	if err := client.SetRaw("users/jack", []byte(`{"a":`), nil); err == nil {
+		t.Fatalf("Expected an error for invalid JSON\n")
+	}
+
+	if _, err := client.PushRaw([]byte(`nope`), nil); err == nil {
+		t.Fatalf("Expected an error for invalid JSON\n")
+	}
+
+	client.StrictKeys = true
+
+	if err := client.SetRaw("users/jack", []byte(`{"a.b":1}`), nil); !errors.Is(err, ErrInvalidKey) {
+		t.Fatalf("Expected the keys to be validated, got %v\n", err)
+	}
+
+	if len(bodies) != 2 {
+		t.Fatalf("Expected invalid data not to be sent, got %q\n", bodies)
+	}
+}
+
+func TestRawMessage(t *testing.T) {
+	var stored []byte
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "PUT" {
+			stored, _ = io.ReadAll(r.Body)
+		}
+		w.Write(stored)
+	}))
+	defer ts.Close()
+
+	client := new(F)
+	client.Init(ts.URL, "", nil)
+
+	blob := json.RawMessage(`{ "b": [1, 2],  "a": "x" }`)
+
+	if _, err := client.Set("blob", blob, nil); err != nil {
+		t.Fatalf("%v\n", err)
+	}
+
+	if string(stored) != string(blob) {
+		t.Fatalf("Expected the message to be written verbatim, got %s\n", stored)
+	}
+
+	var raw json.RawMessage
+	if _, err := client.ChildE("blob", nil, &raw); err != nil || string(raw) != string(blob) {
+		t.Fatalf("Expected the message to be read verbatim, got %s and %v\n", raw, err)
+	}
+
+	if _, err := client.Set("blob", &raw, nil); err != nil || string(stored) != string(blob) {
+		t.Fatalf("Expected a pointer to the message to be written verbatim, got %s and %v\n", stored, err)
+	}
+
+	client.StreamDecode = true
+
+	raw = nil
+	if _, err := client.ChildE("blob", nil, &raw); err != nil || string(raw) != string(blob) {
+		t.Fatalf("Expected the message to be streamed verbatim, got %s and %v\n", raw, err)
+	}
+
+	if _, err := client.Set("blob", json.RawMessage(`{"a":`), nil); err == nil {
+		t.Fatalf("Expected an error for an invalid message\n")
+	}
+}
+
 func TestSet(t *testing.T) {
 	c1 := new(F)
 	c1.Init(testUrl+"/users", testAuth, nil)
@@ -103,3 +270,1232 @@ func TestRemovet(t *testing.T) {
 		t.Fatalf("%v\n", err)
 	}
 }
+
+func TestChildContextCanceled(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer ts.Close()
+
+	client := new(F)
+	client.Init(ts.URL, "", nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := client.RemoveContext(ctx, "users", nil)
+
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled, got %v\n", err)
+	}
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (fn roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return fn(r)
+}
+
+func TestHTTPClient(t *testing.T) {
+	var calls int
+
+	client := new(F)
+	client.HTTPClient = &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		calls++
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(strings.NewReader(`{"first":"Jack"}`)),
+			Request:    r}, nil
+	})}
+	client.Init("https://example.firebaseio.com", "", nil)
+
+	r := client.Child("users/jack", nil, nil)
+
+	if r == nil {
+		t.Fatalf("No child returned from the server\n")
+	}
+
+	if r.HTTPClient != client.HTTPClient {
+		t.Fatalf("HTTPClient was not propagated to the child\n")
+	}
+
+	if calls != 1 {
+		t.Fatalf("Expected 1 call through the custom transport, got %d\n", calls)
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer ts.Close()
+
+	client := new(F)
+	client.Init(ts.URL, "", nil)
+	client.Timeout = 10 * time.Millisecond
+
+	err := client.Remove("users", nil)
+
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected context.DeadlineExceeded, got %v\n", err)
+	}
+}
+
+func TestInitTimeout(t *testing.T) {
+	client := new(F)
+	client.Init("https://example.firebaseio.com", "", nil)
+
+	if client.Timeout != DefaultTimeout {
+		t.Fatalf("Expected the default timeout, got %v\n", client.Timeout)
+	}
+
+	client = &F{Timeout: time.Minute}
+	client.Init("https://example.firebaseio.com", "", nil)
+
+	if client.Timeout != time.Minute {
+		t.Fatalf("Expected the timeout set before Init to be kept, got %v\n", client.Timeout)
+	}
+}
+
+func TestChildE(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{not json`))
+	}))
+	defer ts.Close()
+
+	client := new(F)
+	client.Init(ts.URL, "", nil)
+
+	_, err := client.ChildE("users", nil, nil)
+
+	if err == nil {
+		t.Fatalf("Expected a decoding error\n")
+	}
+
+	if !strings.Contains(err.Error(), ts.URL+"/users") {
+		t.Fatalf("Expected the error to mention the URL, got %v\n", err)
+	}
+}
+
+func TestKeys(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("shallow") != "true" {
+			t.Errorf("Expected a shallow query, got %q\n", r.URL.RawQuery)
+		}
+		w.Write([]byte(`{"b":true,"c":true,"a":true}`))
+	}))
+	defer ts.Close()
+
+	client := new(F)
+	client.Init(ts.URL, "", nil)
+
+	keys, err := client.Keys("users")
+
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+
+	if strings.Join(keys, ",") != "a,b,c" {
+		t.Fatalf("Expected sorted keys, got %v\n", keys)
+	}
+}
+
+func TestSetSilent(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("print") != "silent" {
+			t.Errorf("Expected print=silent, got %q\n", r.URL.RawQuery)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+
+	client := new(F)
+	client.Init(ts.URL, "", nil)
+
+	name := &Name{First: "Jack", Last: "Sparrow"}
+	r, err := client.SetSilent("users/jack", name, nil)
+
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+
+	if r.Value() != name {
+		t.Fatalf("Expected the written value, got %v\n", r.Value())
+	}
+
+	if _, err := client.Push(name, map[string]string{"print": "silent"}); err != ErrSilentPush {
+		t.Fatalf("Expected ErrSilentPush, got %v\n", err)
+	}
+}
+
+func TestRawChild(t *testing.T) {
+	const pretty = "{\n  \"first\" : \"Jack\"\n}"
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("print") != "pretty" {
+			t.Errorf("Expected print=pretty, got %q\n", r.URL.RawQuery)
+		}
+		w.Write([]byte(pretty))
+	}))
+	defer ts.Close()
+
+	client := new(F)
+	client.Init(ts.URL, "", nil)
+
+	b, err := client.RawChild("users/jack", NewQuery().Pretty().Params())
+
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+
+	if string(b) != pretty {
+		t.Fatalf("Expected the raw response, got %q\n", b)
+	}
+}
+
+func TestChildIntoPointer(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"First":"Jack","Last":"Sparrow"}`))
+	}))
+	defer ts.Close()
+
+	client := new(F)
+	client.Init(ts.URL, "", nil)
+
+	var name Name
+	r := client.Child("users/jack", nil, &name)
+
+	if r == nil {
+		t.Fatalf("No child returned from the server\n")
+	}
+
+	if name.First != "Jack" || name.Last != "Sparrow" {
+		t.Fatalf("Expected the struct to be populated, got %+v\n", name)
+	}
+
+	if r.Value() != &name {
+		t.Fatalf("Expected the value to be the decoded struct, got %v\n", r.Value())
+	}
+}
+
+func TestGzip(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Accept-Encoding") != "gzip" {
+			w.Write([]byte(`"plain"`))
+			return
+		}
+
+		w.Header().Set("Content-Encoding", "gzip")
+		zw := gzip.NewWriter(w)
+		zw.Write([]byte(`"compressed"`))
+		zw.Close()
+	}))
+	defer ts.Close()
+
+	client := new(F)
+	client.Init(ts.URL, "", nil)
+
+	if v := client.Child("data", nil, nil).Value(); v != "compressed" {
+		t.Fatalf("Expected a compressed response, got %v\n", v)
+	}
+
+	client.DisableGzip = true
+
+	if v := client.Child("data", nil, nil).Value(); v != "plain" {
+		t.Fatalf("Expected a plain response, got %v\n", v)
+	}
+}
+
+func TestCompressRequests(t *testing.T) {
+	var bodies []string
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body io.Reader = r.Body
+		if r.Header.Get("Content-Encoding") == "gzip" {
+			zr, err := gzip.NewReader(r.Body)
+			if err != nil {
+				t.Errorf("%v\n", err)
+				return
+			}
+			body = zr
+		}
+
+		b, _ := io.ReadAll(body)
+		bodies = append(bodies, r.Header.Get("Content-Encoding")+" "+string(b))
+		w.Write(b)
+	}))
+	defer ts.Close()
+
+	client := new(F)
+	client.Init(ts.URL, "", nil)
+	client.CompressRequests = 10
+
+	large := strings.Repeat("a", 20)
+
+	if _, err := client.Set("small", "a", nil); err != nil {
+		t.Fatalf("%v\n", err)
+	}
+
+	if _, err := client.Set("large", large, nil); err != nil {
+		t.Fatalf("%v\n", err)
+	}
+
+	expected := []string{` "a"`, `gzip "` + large + `"`}
+	if fmt.Sprint(bodies) != fmt.Sprint(expected) {
+		t.Fatalf("Expected %q, got %q\n", expected, bodies)
+	}
+}
+
+func TestEscapePath(t *testing.T) {
+	var paths []string
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.EscapedPath())
+		w.Write([]byte(`null`))
+	}))
+	defer ts.Close()
+
+	client := new(F)
+	client.Init(ts.URL, "", nil)
+
+	for _, p := range []string{"a b", "users/a?b", "ключ", "a%b/c#d"} {
+		if _, err := client.ChildE(p, nil, nil); err != nil {
+			t.Fatalf("%v\n", err)
+		}
+	}
+
+	expected := []string{
+		"/a%20b.json",
+		"/users/a%3Fb.json",
+		"/%D0%BA%D0%BB%D1%8E%D1%87.json",
+		"/a%25b/c%23d.json",
+	}
+
+	for i, p := range expected {
+		if paths[i] != p {
+			t.Fatalf("Expected %s, got %s\n", p, paths[i])
+		}
+	}
+}
+
+func TestUrl(t *testing.T) {
+	root := &F{Url: "https://example.firebaseio.com"}
+	nested := &F{Url: "https://example.firebaseio.com/users/"}
+
+	tests := []struct {
+		f        *F
+		path     string
+		expected string
+	}{
+		{root, "", "https://example.firebaseio.com/.json"},
+		{root, "/", "https://example.firebaseio.com/.json"},
+		{root, "users", "https://example.firebaseio.com/users.json"},
+		{root, "/users/jack/", "https://example.firebaseio.com/users/jack.json"},
+		{root, "users//jack", "https://example.firebaseio.com/users/jack.json"},
+		{nested, "", "https://example.firebaseio.com/users.json"},
+		{nested, "jack/name", "https://example.firebaseio.com/users/jack/name.json"},
+		{root, "users.json", "https://example.firebaseio.com/users.json"},
+		{root, ".json", "https://example.firebaseio.com/.json"},
+	}
+
+	for _, test := range tests {
+		if u := jsonUrl(test.f.url(test.path)); u != test.expected {
+			t.Errorf("Expected %s for %q, got %s\n", test.expected, test.path, u)
+		}
+	}
+}
+
+func TestPushKey(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"name":"-JSOpn9ZC54A4P4RoqVa"}`))
+	}))
+	defer ts.Close()
+
+	client := new(F)
+	client.Init(ts.URL+"/users", "", nil)
+
+	r, err := client.Push(&Name{First: "Jack"}, nil)
+
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+
+	if r.Key() != "-JSOpn9ZC54A4P4RoqVa" {
+		t.Fatalf("Expected the generated key, got %q\n", r.Key())
+	}
+}
+
+func TestKey(t *testing.T) {
+	root := &F{Url: "https://example.firebaseio.com"}
+
+	tests := []struct {
+		f        *F
+		expected string
+	}{
+		{root, ""},
+		{&F{Url: "https://example.firebaseio.com/"}, ""},
+		{root.derive(root.url("users")), "users"},
+		{root.derive(root.url("users/jack/")), "jack"},
+		{root.derive(root.url("users/a b")), "a b"},
+		{root.derive(root.url("users/ключ")), "ключ"},
+	}
+
+	for _, test := range tests {
+		if k := test.f.Key(); k != test.expected {
+			t.Errorf("Expected %q for %s, got %q\n", test.expected, test.f.Url, k)
+		}
+	}
+}
+
+func TestParent(t *testing.T) {
+	root := &F{Url: "https://example.firebaseio.com", Auth: "token"}
+
+	if root.Parent() != nil {
+		t.Fatalf("Expected no parent for the root\n")
+	}
+
+	tests := []struct {
+		f        *F
+		expected string
+	}{
+		{root.derive(root.url("users")), "https://example.firebaseio.com"},
+		{root.derive(root.url("users/jack")), "https://example.firebaseio.com/users"},
+		{&F{Url: "https://example.firebaseio.com/users/jack/"}, "https://example.firebaseio.com/users"},
+		{root.derive(root.url("users/a b/name")), "https://example.firebaseio.com/users/a%20b"},
+	}
+
+	for _, test := range tests {
+		if p := test.f.Parent(); p == nil || p.Url != test.expected {
+			t.Errorf("Expected parent %s for %s, got %v\n", test.expected, test.f.Url, p)
+		}
+	}
+
+	if p := root.derive(root.url("users/jack")).Parent(); p.Auth != "token" {
+		t.Fatalf("Expected the parent to keep the auth token\n")
+	}
+}
+
+func TestRootRef(t *testing.T) {
+	client := new(F)
+	client.Init("https://example.firebaseio.com/app", "token", nil)
+
+	deep := client.derive(client.url("users/jack/name"))
+
+	if r := deep.Root(); r.Url != "https://example.firebaseio.com/app" || r.Auth != "token" {
+		t.Fatalf("Unexpected root %s\n", r.Url)
+	}
+
+	if r := deep.Ref("/posts/1"); r.Url != "https://example.firebaseio.com/app/posts/1" || r.Auth != "token" {
+		t.Fatalf("Unexpected reference %s\n", r.Url)
+	}
+}
+
+func TestUpdateChildren(t *testing.T) {
+	var body map[string]interface{}
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "PATCH" || r.URL.Path != "/app.json" {
+			t.Errorf("Unexpected request %s %s\n", r.Method, r.URL.Path)
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		w.Write([]byte(`{}`))
+	}))
+	defer ts.Close()
+
+	client := new(F)
+	client.Init(ts.URL+"/app", "", nil)
+
+	err := client.UpdateChildren(map[string]interface{}{
+		"users/1/name":    "x",
+		"/posts/1/author": "1",
+	}, nil)
+
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+
+	if body["users/1/name"] != "x" || body["/posts/1/author"] != "1" {
+		t.Fatalf("Unexpected update %v\n", body)
+	}
+
+	for _, k := range []string{"../users", "users/../../x", ""} {
+		if err := client.UpdateChildren(map[string]interface{}{k: 1}, nil); err == nil {
+			t.Fatalf("Expected an error for %q\n", k)
+		}
+	}
+}
+
+func TestConcurrentUse(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"a":1}`))
+	}))
+	defer ts.Close()
+
+	client := new(F)
+	client.Init(ts.URL, "", nil)
+
+	done := make(chan bool)
+	for i := 0; i < 4; i++ {
+		go func() {
+			client.Value()
+			client.Update("", map[string]int{"a": 2}, nil)
+			client.Child("a", nil, nil)
+			done <- true
+		}()
+	}
+
+	for i := 0; i < 4; i++ {
+		<-done
+	}
+}
+
+func TestValueCached(t *testing.T) {
+	var calls int
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Write([]byte(`{"a":1}`))
+	}))
+	defer ts.Close()
+
+	client := new(F)
+	client.Init(ts.URL, "", nil)
+
+	client.Value()
+	client.Value()
+
+	if calls != 1 {
+		t.Fatalf("Expected 1 call, got %d\n", calls)
+	}
+}
+
+func TestWithParam(t *testing.T) {
+	var queries []string
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		queries = append(queries, r.URL.RawQuery)
+		w.Write([]byte(`null`))
+	}))
+	defer ts.Close()
+
+	client := new(F)
+	client.Init(ts.URL, "", nil)
+
+	q := client.WithParam("orderBy", `"name"`).WithParam("limitToFirst", "10")
+	q.Child("users", map[string]string{"limitToFirst": "5"}, nil)
+	client.Child("users", nil, nil)
+
+	expected := []string{
+		"limitToFirst=5&orderBy=%22name%22",
+		"",
+	}
+
+	for i, e := range expected {
+		if queries[i] != e {
+			t.Fatalf("Expected query %q, got %q\n", e, queries[i])
+		}
+	}
+}
+
+func TestClone(t *testing.T) {
+	var requests []string
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.URL.RawQuery+" "+r.Header.Get("X-Tenant"))
+		w.Write([]byte(`"remote"`))
+	}))
+	defer ts.Close()
+
+	client := new(F)
+	client.Init(ts.URL, "", nil)
+	client.Header = http.Header{"X-Tenant": {"a"}}
+
+	f := client.WithParam("print", "pretty")
+	f.setValue("cached", false)
+
+	c := f.Clone()
+	c.Header.Set("X-Tenant", "b")
+
+	if v := c.Value(); v != "remote" {
+		t.Fatalf("Expected the clone not to share the cached value, got %v\n", v)
+	}
+
+	if v := f.Value(); v != "cached" {
+		t.Fatalf("Expected the original value to be kept, got %v\n", v)
+	}
+
+	// chained options keep the previous ones
+	client.WithParam("print", "pretty").WithHeader("X-Tenant", "c").Child("", nil, nil)
+
+	expected := []string{"print=pretty b", "print=pretty c"}
+	if fmt.Sprint(requests) != fmt.Sprint(expected) || client.Header.Get("X-Tenant") != "a" {
+		t.Fatalf("Expected %q, got %q\n", expected, requests)
+	}
+
+	if c.HTTPClient != client.HTTPClient {
+		t.Fatalf("Expected the HTTP client to be shared\n")
+	}
+}
+
+// countingApi counts the calls made through the Api it wraps.
+type countingApi struct {
+	next  Api
+	calls int32
+}
+
+func (a *countingApi) Call(method, path, auth string, body []byte, params map[string]string) ([]byte, error) {
+	atomic.AddInt32(&a.calls, 1)
+	return a.next.Call(method, path, auth, body, params)
+}
+
+func (a *countingApi) CallContext(ctx context.Context, method, path, auth string, body []byte, params map[string]string) ([]byte, error) {
+	atomic.AddInt32(&a.calls, 1)
+	return a.next.(ContextApi).CallContext(ctx, method, path, auth, body, params)
+}
+
+func TestSetApi(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing.json" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`"value"`))
+	}))
+	defer ts.Close()
+
+	client := new(F)
+	client.Init(ts.URL, "", nil)
+
+	if _, ok := client.Api().(ContextApi); !ok {
+		t.Fatalf("Expected the built-in client to support contexts\n")
+	}
+
+	api := &countingApi{next: client.Api()}
+	client.SetApi(api)
+
+	if v := client.Ref("a").Child("b", nil, nil).Value(); v != "value" {
+		t.Fatalf("Expected calls to be delegated, got %v\n", v)
+	}
+
+	if _, err := client.ChildE("missing", nil, nil); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Expected errors to be passed through, got %v\n", err)
+	}
+
+	if api.calls != 2 || client.Api() != api {
+		t.Fatalf("Expected 2 calls through the wrapper, got %d\n", api.calls)
+	}
+}
+
+func TestCallFull(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Test", "yes")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"name":"key"}`))
+	}))
+	defer ts.Close()
+
+	client := new(F)
+	client.Init(ts.URL, "", nil)
+
+	res, err := client.CallFull("POST", "users", []byte(`{}`), nil)
+
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+
+	if res.StatusCode != http.StatusCreated || res.Header.Get("X-Test") != "yes" || string(res.Body) != `{"name":"key"}` {
+		t.Fatalf("Unexpected response %+v\n", res)
+	}
+}
+
+func TestEmulatorHost(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, "/users") || r.URL.Query().Get("ns") != "my-db" {
+			t.Errorf("Unexpected request %s\n", r.URL)
+		}
+		if r.Header.Get("Authorization") != "Bearer owner" || r.URL.Query().Get("auth") != "" {
+			t.Errorf("Expected the owner credential\n")
+		}
+		w.Write([]byte(`null`))
+	}))
+	defer ts.Close()
+
+	t.Setenv(EmulatorHostEnv, strings.TrimPrefix(ts.URL, "http://"))
+
+	client := new(F)
+	client.Init("https://my-db.firebaseio.com", "secret", nil)
+
+	// no token is needed to call the emulator as its owner
+	tokens := new(countingTokenSource)
+	client.TokenSource = tokens
+
+	r, err := client.ChildE("users", nil, nil)
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+
+	// derived references must keep calling the emulator
+	if _, err := r.ChildE("a", nil, nil); err != nil {
+		t.Fatalf("%v\n", err)
+	}
+
+	if _, _, err := r.GetWithETag("b"); err != nil {
+		t.Fatalf("%v\n", err)
+	}
+
+	if *tokens != 0 {
+		t.Fatalf("Expected no token to be obtained, got %d\n", *tokens)
+	}
+}
+
+func TestInitValidatesUrl(t *testing.T) {
+	client := new(F)
+
+	for _, root := range []string{"", "example.firebaseio.com", "https://", "://bad"} {
+		if err := client.Init(root, "", nil); err == nil {
+			t.Errorf("Expected an error for %q\n", root)
+		}
+	}
+
+	if err := client.Init("https://example.firebaseio.com/", "", nil); err != nil {
+		t.Fatalf("%v\n", err)
+	}
+
+	if client.Url != "https://example.firebaseio.com" {
+		t.Fatalf("Expected the trailing slash to be trimmed, got %s\n", client.Url)
+	}
+}
+
+func TestUserAgent(t *testing.T) {
+	var agents []string
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		agents = append(agents, r.Header.Get("User-Agent"))
+		w.Write([]byte(`null`))
+	}))
+	defer ts.Close()
+
+	client := new(F)
+	client.Init(ts.URL, "", nil)
+
+	client.Child("a", nil, nil)
+
+	client.Header = http.Header{"User-Agent": {"header/1"}}
+	client.Child("a", nil, nil)
+
+	client.UserAgent = "my-service/2"
+	client.Ref("a").Child("b", nil, nil)
+
+	expected := []string{DefaultUserAgent, "header/1", "my-service/2"}
+	if fmt.Sprint(agents) != fmt.Sprint(expected) {
+		t.Fatalf("Expected %q, got %q\n", expected, agents)
+	}
+
+	if !strings.HasSuffix(DefaultUserAgent, "/"+Version) {
+		t.Fatalf("Expected the version in %s\n", DefaultUserAgent)
+	}
+}
+
+func TestHeader(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Client-Version") != "1.0" || r.Header.Get("X-Correlation-Id") != "abc" {
+			t.Errorf("Missing headers in %v\n", r.Header)
+		}
+		w.Write([]byte(`null`))
+	}))
+	defer ts.Close()
+
+	client := new(F)
+	client.Init(ts.URL, "", nil)
+	client.Header = http.Header{"X-Client-Version": {"1.0"}}
+
+	r := client.WithHeader("X-Correlation-Id", "abc")
+	r.Child("users", nil, nil).Child("a", nil, nil)
+
+	if client.Header.Get("X-Correlation-Id") != "" {
+		t.Fatalf("Expected WithHeader not to modify the receiver\n")
+	}
+}
+
+func TestCallStream(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Encoding", "gzip")
+		zw := gzip.NewWriter(w)
+		zw.Write([]byte(`{"First":"Jack","Last":"Sparrow"}`))
+		zw.Close()
+	}))
+	defer ts.Close()
+
+	client := new(F)
+	client.Init(ts.URL, "", nil)
+
+	body, err := client.CallStream("GET", "users/jack", nil, nil)
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	defer body.Close()
+
+	var name Name
+	if err := json.NewDecoder(body).Decode(&name); err != nil {
+		t.Fatalf("%v\n", err)
+	}
+
+	if name.First != "Jack" || name.Last != "Sparrow" {
+		t.Fatalf("Expected the struct to be populated, got %+v\n", name)
+	}
+}
+
+func TestStreamDecode(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing.json" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(`{"First":"Jack","Last":"Sparrow"}`))
+	}))
+	defer ts.Close()
+
+	client := new(F)
+	client.Init(ts.URL, "", nil)
+	client.StreamDecode = true
+
+	var name Name
+	r, err := client.ChildE("users/jack", nil, &name)
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+
+	if name.First != "Jack" || r.Value() != &name {
+		t.Fatalf("Expected the struct to be populated, got %+v\n", name)
+	}
+
+	if v := client.Child("users/jack", nil, nil).Value(); v.(map[string]interface{})["Last"] != "Sparrow" {
+		t.Fatalf("Expected a decoded map, got %v\n", v)
+	}
+
+	if _, err := client.ChildE("missing", nil, nil); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Expected ErrNotFound, got %v\n", err)
+	}
+}
+
+func TestHooks(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/users/missing.json" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(`null`))
+	}))
+	defer ts.Close()
+
+	var requests, responses []string
+
+	client := new(F)
+	client.Init(ts.URL, "secret", nil)
+	client.OnRequest = func(method, path string) {
+		requests = append(requests, method+" "+path)
+	}
+	client.OnResponse = func(method, path string, status int, dur time.Duration, err error) {
+		responses = append(responses, fmt.Sprintf("%s %s %d %v", method, path, status, err != nil))
+	}
+
+	client.Child("users", nil, nil).Child("missing", nil, nil)
+
+	if len(requests) != 2 || requests[0] != "GET "+ts.URL+"/users.json?auth="+redacted {
+		t.Fatalf("Unexpected requests %q\n", requests)
+	}
+
+	if len(responses) != 2 || !strings.HasSuffix(responses[0], " 200 false") || !strings.HasSuffix(responses[1], " 404 true") {
+		t.Fatalf("Unexpected responses %q\n", responses)
+	}
+}
+
+func TestBuildRequest(t *testing.T) {
+	q := NewQuery().OrderByChild("name").EqualTo("Jack")
+
+	req, err := BuildRequest("PUT", "https://example.firebaseio.com/users", "secret", []byte(`{"a":1}`), q.Params())
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+
+	if req.Method != "PUT" || req.URL.Path != "/users.json" {
+		t.Fatalf("Unexpected request %s %s\n", req.Method, req.URL)
+	}
+
+	qs := req.URL.Query()
+	if qs.Get("auth") != "secret" || qs.Get("orderBy") != `"name"` || qs.Get("equalTo") != `"Jack"` {
+		t.Fatalf("Unexpected query %s\n", req.URL.RawQuery)
+	}
+
+	body, _ := io.ReadAll(req.Body)
+	if string(body) != `{"a":1}` {
+		t.Fatalf("Unexpected body %s\n", body)
+	}
+}
+
+func TestGet(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/users/missing.json" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(`{"First":"Jack","Last":"Sparrow"}`))
+	}))
+	defer ts.Close()
+
+	client := new(F)
+	client.Init(ts.URL, "", nil)
+
+	var name Name
+	if err := client.Get("users/jack", nil, &name); err != nil {
+		t.Fatalf("%v\n", err)
+	}
+
+	if name.First != "Jack" || name.Last != "Sparrow" {
+		t.Fatalf("Expected the struct to be populated, got %+v\n", name)
+	}
+
+	if err := client.Get("users/missing", nil, &name); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Expected ErrNotFound, got %v\n", err)
+	}
+}
+
+func TestHasValue(t *testing.T) {
+	var calls int
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if r.URL.Path == "/users/jack.json" {
+			w.Write([]byte(`{"First":"Jack","Last":"Sparrow"}`))
+			return
+		}
+		w.Write([]byte(` null`))
+	}))
+	defer ts.Close()
+
+	client := new(F)
+	client.Init(ts.URL, "", nil)
+
+	r := client.Child("users/nobody", nil, nil)
+	if r == nil {
+		t.Fatalf("Expected a reference for a null value\n")
+	}
+
+	if r.HasValue() || r.Value() != nil {
+		t.Fatalf("Expected no value, got %v\n", r.Value())
+	}
+
+	var name Name
+	if r := client.Child("users/nobody", nil, &name); r == nil || r.HasValue() {
+		t.Fatalf("Expected no value when decoding into a pointer\n")
+	}
+
+	if r := client.Child("users/jack", nil, &name); r == nil || !r.HasValue() {
+		t.Fatalf("Expected a value\n")
+	}
+
+	if calls != 3 {
+		t.Fatalf("Expected null values not to be looked up again, got %d calls\n", calls)
+	}
+
+	if !client.Ref("users/jack").HasValue() {
+		t.Fatalf("Expected HasValue to look up the value\n")
+	}
+}
+
+func TestRawValue(t *testing.T) {
+	fake := NewFake()
+	fake.Seed("blobs", map[string]interface{}{"a": "aGVsbG8=", "b": map[string]int{"size": 5}})
+
+	client := new(F)
+	client.Init("https://example.firebaseio.com", "", fake)
+
+	if v := client.Child("blobs/a", nil, nil).RawValue(); string(v) != "aGVsbG8=" {
+		t.Fatalf("Expected the string unquoted, got %s\n", v)
+	}
+
+	var s string
+	if v := client.Child("blobs/a", nil, &s).RawValue(); string(v) != "aGVsbG8=" {
+		t.Fatalf("Expected the decoded string unquoted, got %s\n", v)
+	}
+
+	if v := client.Ref("blobs/b").RawValue(); string(v) != `{"size":5}` {
+		t.Fatalf("Expected JSON for objects, got %s\n", v)
+	}
+
+	if v := client.Ref("blobs/missing").RawValue(); v != nil {
+		t.Fatalf("Expected nil for null, got %s\n", v)
+	}
+}
+
+func TestSetRoot(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "PUT" || r.URL.Path != "/.json" {
+			t.Errorf("Unexpected request %s %s\n", r.Method, r.URL.Path)
+		}
+		io.Copy(w, r.Body)
+	}))
+	defer ts.Close()
+
+	client := new(F)
+	client.Init(ts.URL, "", nil)
+
+	users := client.Ref("users")
+	users.setValue("stale", false)
+
+	r, err := users.SetRoot(map[string]string{"a": "b"}, nil)
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+
+	if r.Url != ts.URL || fmt.Sprint(r.Value()) != "map[a:b]" {
+		t.Fatalf("Unexpected root %s with value %v\n", r.Url, r.Value())
+	}
+
+	if users.loaded {
+		t.Fatalf("Expected the cached value to be cleared\n")
+	}
+}
+
+func TestMethodOverride(t *testing.T) {
+	var calls []string
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, r.Method+" "+r.Header.Get("X-HTTP-Method-Override"))
+		w.Write([]byte(`{"name":"-Nabc"}`))
+	}))
+	defer ts.Close()
+
+	var hooked []string
+
+	client := new(F)
+	client.Init(ts.URL, "", nil)
+	client.MethodOverride = true
+	client.OnRequest = func(method, url string) {
+		hooked = append(hooked, method)
+	}
+
+	client.Child("a", nil, nil)
+	client.Set("a", 1, nil)
+	client.Update("a", map[string]int{"b": 1}, nil)
+	client.Remove("a", nil)
+	client.Ref("a").Push(1, nil)
+
+	expected := []string{"GET ", "POST PUT", "POST PATCH", "POST DELETE", "POST "}
+	if fmt.Sprint(calls) != fmt.Sprint(expected) {
+		t.Fatalf("Expected %q, got %q\n", expected, calls)
+	}
+
+	if fmt.Sprint(hooked) != "[GET PUT PATCH DELETE POST]" {
+		t.Fatalf("Expected hooks to see the original methods, got %v\n", hooked)
+	}
+}
+
+func TestPing(t *testing.T) {
+	status := http.StatusOK
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/.json" || r.URL.Query().Get("shallow") != "true" {
+			t.Errorf("Unexpected request %s\n", r.URL)
+		}
+		w.WriteHeader(status)
+		w.Write([]byte(`{"users":true}`))
+	}))
+
+	client := new(F)
+	client.Init(ts.URL, "", nil)
+
+	if err := client.Ref("users").Ping(context.Background()); err != nil {
+		t.Fatalf("%v\n", err)
+	}
+
+	status = http.StatusUnauthorized
+
+	if err := client.Ping(context.Background()); !errors.Is(err, ErrUnauthorized) {
+		t.Fatalf("Expected ErrUnauthorized, got %v\n", err)
+	}
+
+	ts.Close()
+
+	var apiErr *APIError
+	if err := client.Ping(context.Background()); err == nil || errors.As(err, &apiErr) {
+		t.Fatalf("Expected a network error, got %v\n", err)
+	}
+}
+
+func TestExists(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("shallow") != "true" {
+			t.Errorf("Expected a shallow request, got %q\n", r.URL.RawQuery)
+		}
+
+		switch r.URL.Path {
+		case "/users.json":
+			w.Write([]byte(`{"jack":true}`))
+		case "/denied.json":
+			w.WriteHeader(http.StatusUnauthorized)
+		default:
+			w.Write([]byte(`null`))
+		}
+	}))
+	defer ts.Close()
+
+	client := new(F)
+	client.Init(ts.URL, "", nil)
+
+	if ok, err := client.Exists("users"); err != nil || !ok {
+		t.Fatalf("Expected users to exist, got %v %v\n", ok, err)
+	}
+
+	if ok, err := client.Exists("posts"); err != nil || ok {
+		t.Fatalf("Expected posts not to exist, got %v %v\n", ok, err)
+	}
+
+	if _, err := client.Exists("denied"); err == nil {
+		t.Fatalf("Expected an error\n")
+	}
+}
+
+func TestMarshalError(t *testing.T) {
+	var buf bytes.Buffer
+
+	client := new(F)
+	client.Init("https://example.firebaseio.com", "", nil)
+	client.Logger = log.New(&buf, "", 0)
+
+	_, err := client.Set("users/jack", make(chan int), nil)
+
+	var typeErr *json.UnsupportedTypeError
+	if !errors.As(err, &typeErr) || !strings.Contains(err.Error(), "marshal value for https://example.firebaseio.com/users/jack") {
+		t.Fatalf("Expected a wrapped marshal error, got %v\n", err)
+	}
+
+	if buf.Len() != 0 {
+		t.Fatalf("Expected nothing to be logged, got %q\n", buf.String())
+	}
+}
+
+func TestMaxResponseBytes(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`"` + strings.Repeat("x", 100) + `"`))
+	}))
+	defer ts.Close()
+
+	client := new(F)
+	client.Init(ts.URL, "", nil)
+	client.MaxResponseBytes = 102
+
+	if _, err := client.ChildE("data", nil, nil); err != nil {
+		t.Fatalf("Expected a response at the limit to be read, got %v\n", err)
+	}
+
+	client.MaxResponseBytes = 50
+
+	if _, err := client.ChildE("data", nil, nil); !errors.Is(err, ErrResponseTooLarge) {
+		t.Fatalf("Expected ErrResponseTooLarge, got %v\n", err)
+	}
+
+	client.StreamDecode = true
+
+	if _, err := client.ChildE("data", nil, nil); !errors.Is(err, ErrResponseTooLarge) {
+		t.Fatalf("Expected ErrResponseTooLarge when streaming, got %v\n", err)
+	}
+}
+
+func TestKeepAlive(t *testing.T) {
+	var conns int32
+
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`null`))
+	}))
+	ts.Config.ConnState = func(c net.Conn, s http.ConnState) {
+		if s == http.StateNew {
+			atomic.AddInt32(&conns, 1)
+		}
+	}
+	ts.Start()
+	defer ts.Close()
+
+	client := new(F)
+	client.Init(ts.URL, "", nil)
+
+	for i := 0; i < 3; i++ {
+		client.Child("data", nil, nil)
+	}
+
+	if n := atomic.LoadInt32(&conns); n != 1 {
+		t.Fatalf("Expected the connection to be reused, got %d connections\n", n)
+	}
+
+	client.CloseConnections = true
+
+	// the idle connection is used once more before being closed
+	for i := 0; i < 3; i++ {
+		client.Child("data", nil, nil)
+	}
+
+	if n := atomic.LoadInt32(&conns); n != 3 {
+		t.Fatalf("Expected a connection per call, got %d connections\n", n)
+	}
+}
+
+// benchmarkCalls measures calls to a TLS server, where opening a connection
+// is the most expensive.
+func benchmarkCalls(b *testing.B, closeConnections bool) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`null`))
+	}))
+	defer ts.Close()
+
+	client := new(F)
+	client.Init(ts.URL, "", nil)
+	client.HTTPClient = ts.Client()
+	client.CloseConnections = closeConnections
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if _, err := client.ChildE("data", nil, nil); err != nil {
+			b.Fatalf("%v\n", err)
+		}
+	}
+}
+
+func BenchmarkKeepAlive(b *testing.B) {
+	benchmarkCalls(b, false)
+}
+
+func BenchmarkCloseConnections(b *testing.B) {
+	benchmarkCalls(b, true)
+}
+
+func TestValueContext(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/denied.json" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"a":1}`))
+	}))
+	defer ts.Close()
+
+	client := new(F)
+	client.Init(ts.URL, "", nil)
+
+	v, err := client.Ref("data").ValueContext(context.Background())
+	if err != nil || v.(map[string]interface{})["a"] != float64(1) {
+		t.Fatalf("Unexpected value %v and error %v\n", v, err)
+	}
+
+	var apiErr *APIError
+	if _, err := client.Ref("denied").ValueContext(context.Background()); !errors.As(err, &apiErr) {
+		t.Fatalf("Expected an APIError, got %v\n", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := client.Ref("data").ValueContext(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled, got %v\n", err)
+	}
+}
@@ -0,0 +1,84 @@
+// Package firebasetest provides an in-memory implementation of the Api
+// interface of the firebase package, to test code using the client without
+// a network:
+//
+//	api := firebasetest.NewApi()
+//	f := new(firebase.F)
+//	f.Init("https://example.firebaseio.com", "", api)
+package firebasetest
+
+import (
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/cosn/firebase"
+)
+
+var _ firebase.Api = (*Api)(nil)
+
+// Api is an in-memory Firebase database implementing the Api interface.
+// It is a firebase.Fake, which applies calls to a JSON tree like Firebase
+// does, whose calls on given paths can be made to fail with SetError.
+// An Api is safe for concurrent use.
+type Api struct {
+	*firebase.Fake
+
+	// mu guards the fields below
+	mu sync.Mutex
+
+	// errors holds the errors returned for given paths
+	errors map[string]error
+}
+
+// NewApi returns an empty database.
+func NewApi() *Api {
+	return &Api{Fake: firebase.NewFake(), errors: map[string]error{}}
+}
+
+// SetError makes the calls on the given path, such as "users/jack", fail
+// with err, e.g. to test how failures are handled. A nil err removes the
+// error.
+func (a *Api) SetError(path string, err error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	key := key(path)
+
+	if err == nil {
+		delete(a.errors, key)
+		return
+	}
+
+	a.errors[key] = err
+}
+
+// Call applies the given HTTP method to the value at the given Firebase URL,
+// unless an error was set for its path.
+func (a *Api) Call(method, path, auth string, body []byte, params map[string]string) ([]byte, error) {
+	a.mu.Lock()
+	err := a.errors[key(path)]
+	a.mu.Unlock()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return a.Fake.Call(method, path, auth, body, params)
+}
+
+// key returns the unescaped path of a Firebase URL or path, without the
+// surrounding slashes, which identifies its errors.
+func key(path string) string {
+	if u, err := url.Parse(path); err == nil {
+		path = u.EscapedPath()
+	}
+
+	path = strings.Trim(strings.TrimSuffix(path, ".json"), "/")
+
+	if up, err := url.PathUnescape(path); err == nil {
+		path = up
+	}
+
+	return path
+}
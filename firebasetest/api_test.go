@@ -0,0 +1,155 @@
+package firebasetest
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/cosn/firebase"
+)
+
+const root = "https://example.firebaseio.com"
+
+func call(t *testing.T, a *Api, method, path, body string) interface{} {
+	res, err := a.Call(method, root+path, "", []byte(body), nil)
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+
+	var v interface{}
+	if err := json.Unmarshal(res, &v); err != nil {
+		t.Fatalf("%v\n", err)
+	}
+
+	return v
+}
+
+func TestApi(t *testing.T) {
+	a := NewApi()
+
+	call(t, a, "PUT", "/users/jack", `{"first":"Jack","last":"Sparrow"}`)
+	call(t, a, "PATCH", "/users", `{"jack/last":"Black","will/first":"Will"}`)
+
+	v := call(t, a, "GET", "/users", "").(map[string]interface{})
+	jack := v["jack"].(map[string]interface{})
+
+	if jack["first"] != "Jack" || jack["last"] != "Black" || v["will"] == nil {
+		t.Fatalf("Unexpected users %v\n", v)
+	}
+
+	call(t, a, "DELETE", "/users/will", "")
+	call(t, a, "PATCH", "/users/jack", `{"first":null}`)
+
+	if v := call(t, a, "GET", "/users", ""); len(v.(map[string]interface{})) != 1 {
+		t.Fatalf("Expected will to be deleted, got %v\n", v)
+	}
+
+	if v := call(t, a, "GET", "/users/jack/first", ""); v != nil {
+		t.Fatalf("Expected null for a deleted value, got %v\n", v)
+	}
+
+	call(t, a, "DELETE", "/users/jack", "")
+
+	if v := call(t, a, "GET", "", ""); v != nil {
+		t.Fatalf("Expected an empty database, got %v\n", v)
+	}
+}
+
+func TestApiPush(t *testing.T) {
+	a := NewApi()
+
+	var names []string
+	for i := 0; i < 5; i++ {
+		r := call(t, a, "POST", "/messages", `"hi"`).(map[string]interface{})
+		names = append(names, r["name"].(string))
+	}
+
+	if !sort.StringsAreSorted(names) || len(names[0]) != 20 || names[0] == names[1] {
+		t.Fatalf("Expected unique sorted push keys, got %v\n", names)
+	}
+
+	if v := call(t, a, "GET", "/messages/"+names[2], ""); v != "hi" {
+		t.Fatalf("Expected the pushed value, got %v\n", v)
+	}
+}
+
+func TestApiServerValues(t *testing.T) {
+	a := NewApi()
+
+	call(t, a, "PUT", "/count", `3`)
+	call(t, a, "PATCH", "", `{"count":{".sv":{"increment":2}}}`)
+
+	if v := call(t, a, "GET", "/count", ""); v != float64(5) {
+		t.Fatalf("Expected the count to be incremented, got %v\n", v)
+	}
+
+	if v := call(t, a, "PUT", "/created", `{".sv":"timestamp"}`); v.(float64) <= 0 {
+		t.Fatalf("Expected a timestamp, got %v\n", v)
+	}
+}
+
+func TestApiSetError(t *testing.T) {
+	a := NewApi()
+	denied := errors.New("permission denied")
+
+	a.SetError("/secrets/", denied)
+
+	if _, err := a.Call("GET", root+"/secrets.json", "", nil, nil); err != denied {
+		t.Fatalf("Expected the canned error, got %v\n", err)
+	}
+
+	a.SetError("secrets", nil)
+
+	if _, err := a.Call("GET", root+"/secrets", "", nil, nil); err != nil {
+		t.Fatalf("%v\n", err)
+	}
+}
+
+func TestApiSilent(t *testing.T) {
+	a := NewApi()
+
+	res, err := a.Call("PUT", root+"/a", "", []byte(`1`), map[string]string{"print": "silent"})
+	if err != nil || len(res) != 0 {
+		t.Fatalf("Expected an empty response, got %q and %v\n", res, err)
+	}
+}
+
+func TestApiClient(t *testing.T) {
+	client := new(firebase.F)
+	client.Init(root, "", NewApi())
+
+	for _, name := range []string{"jack", "will"} {
+		if _, err := client.Set("users/"+name, map[string]string{"name": name}, nil); err != nil {
+			t.Fatalf("%v\n", err)
+		}
+	}
+
+	keys, err := client.Keys("users")
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+
+	if expected := []string{"jack", "will"}; !reflect.DeepEqual(keys, expected) {
+		t.Fatalf("Expected keys %v, got %v\n", expected, keys)
+	}
+
+	ref, err := client.Push("hi", nil)
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+
+	var v string
+	if _, err := client.ChildE(ref.Key(), firebase.NewQuery().Pretty().Params(), &v); err != nil || v != "hi" {
+		t.Fatalf("Expected the pushed value, got %q and %v\n", v, err)
+	}
+
+	if err := client.Remove("users/will", nil); err != nil {
+		t.Fatalf("%v\n", err)
+	}
+
+	if ok, err := client.Exists("users/will"); err != nil || ok {
+		t.Fatalf("Expected will to be removed, got %v and %v\n", ok, err)
+	}
+}
@@ -0,0 +1,48 @@
+//go:build go1.18
+
+package firebase
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// Get reads the value at the given path of f and decodes it into a T.
+// A missing value decodes as the zero value of T.
+func Get[T any](f *F, path string, params map[string]string) (T, error) {
+	var v T
+
+	_, err := f.ChildE(path, params, &v)
+
+	return v, err
+}
+
+// Set overwrites the value at the given path of f and returns the value
+// written as echoed back by Firebase, or value itself with print=silent.
+func Set[T any](f *F, path string, value T, params map[string]string) (T, error) {
+	var ret T
+
+	u := f.url(path)
+
+	body, err := f.marshal(u, value, false)
+	if err != nil {
+		return ret, err
+	}
+
+	res, err := f.call(context.Background(), "PUT", u, body, params)
+	if err != nil {
+		return ret, fmt.Errorf("firebase: set %s: %w", u, err)
+	}
+
+	if len(res) == 0 || params["print"] == "silent" {
+		return value, nil
+	}
+
+	err = json.Unmarshal(res, &ret)
+	if err != nil {
+		return ret, &DecodeError{Path: u, Raw: res, Err: err}
+	}
+
+	return ret, nil
+}
@@ -0,0 +1,51 @@
+//go:build go1.18
+
+package firebase
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGenericGetSet(t *testing.T) {
+	var stored []byte
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "PUT" {
+			stored, _ = io.ReadAll(r.Body)
+		}
+		w.Write(stored)
+	}))
+	defer ts.Close()
+
+	client := new(F)
+	client.Init(ts.URL, "", nil)
+
+	set, err := Set(client, "users/jack", Name{First: "Jack", Last: "Sparrow"}, nil)
+
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+
+	if set.First != "Jack" {
+		t.Fatalf("Unexpected value %+v\n", set)
+	}
+
+	got, err := Get[Name](client, "users/jack", nil)
+
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+
+	if got != set {
+		t.Fatalf("Expected %+v, got %+v\n", set, got)
+	}
+
+	_, err = Get[int](client, "users/jack", nil)
+
+	if err == nil {
+		t.Fatalf("Expected a decoding error\n")
+	}
+}
@@ -0,0 +1,91 @@
+package firebase
+
+import (
+	"errors"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// redacted replaces credentials in logs and errors.
+const redacted = "REDACTED"
+
+// bearer matches credentials sent as bearer tokens.
+var bearer = regexp.MustCompile(`Bearer\s+[^\s"']+`)
+
+// Logger is the interface used to log calls and failures.
+// A *log.Logger can be used as a Logger.
+type Logger interface {
+	Printf(format string, v ...interface{})
+}
+
+// logf logs through l, which can be nil to discard the message.
+func logf(l Logger, format string, v ...interface{}) {
+	if l != nil {
+		l.Printf(format, v...)
+	}
+}
+
+// credentials lists the query parameters carrying credentials.
+var credentials = []string{"auth", "access_token"}
+
+// redact returns the given URL with the value of its credential parameters
+// replaced, so that it can be logged or returned without leaking them.
+func redact(u string) string {
+	p, err := url.Parse(u)
+	if err != nil {
+		return u
+	}
+
+	qs := p.Query()
+	found := false
+
+	for _, k := range credentials {
+		if _, ok := qs[k]; ok {
+			qs.Set(k, redacted)
+			found = true
+		}
+	}
+
+	if !found {
+		return u
+	}
+
+	p.RawQuery = qs.Encode()
+
+	return p.String()
+}
+
+// secrets returns the credentials sent in the query of u.
+func secrets(u *url.URL) []string {
+	var ret []string
+
+	qs := u.Query()
+	for _, k := range credentials {
+		ret = append(ret, qs[k]...)
+	}
+
+	return ret
+}
+
+// scrub removes the given secrets and any bearer token from s.
+func scrub(s string, secrets ...string) string {
+	for _, secret := range secrets {
+		if secret != "" {
+			s = strings.ReplaceAll(s, secret, redacted)
+		}
+	}
+
+	return bearer.ReplaceAllString(s, "Bearer "+redacted)
+}
+
+// redactError removes credentials from the URL of errors returned by
+// http.Client, which would otherwise leak into logs and error messages.
+func redactError(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		urlErr.URL = redact(urlErr.URL)
+	}
+
+	return err
+}
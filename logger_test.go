@@ -0,0 +1,58 @@
+package firebase
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestLoggerRedactsAuth(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`null`))
+	}))
+	defer ts.Close()
+
+	var buf bytes.Buffer
+
+	client := new(F)
+	client.Init(ts.URL, "secret-token", nil)
+	client.Logger = log.New(&buf, "", 0)
+
+	client.Child("users", nil, nil)
+
+	if !strings.Contains(buf.String(), "Calling GET") {
+		t.Fatalf("Expected the call to be logged, got %q\n", buf.String())
+	}
+
+	if strings.Contains(buf.String(), "secret-token") {
+		t.Fatalf("Expected the auth token to be redacted, got %q\n", buf.String())
+	}
+}
+
+func TestErrorsRedactAuth(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "invalid token "+r.URL.Query().Get("auth")+", Authorization: Bearer abc.def", http.StatusUnauthorized)
+	}))
+	defer ts.Close()
+
+	client := new(F)
+	client.Init(ts.URL, "secret-token", nil)
+
+	_, err := client.ChildE("users", nil, nil)
+
+	if err == nil || strings.Contains(err.Error(), "secret-token") || strings.Contains(err.Error(), "abc.def") {
+		t.Fatalf("Expected credentials to be redacted, got %v\n", err)
+	}
+
+	// an unreachable server makes http.Client return the URL in its error
+	client.Init("http://127.0.0.1:1", "secret-token", nil)
+
+	_, err = client.ChildE("users", nil, nil)
+
+	if err == nil || strings.Contains(err.Error(), "secret-token") {
+		t.Fatalf("Expected credentials to be redacted, got %v\n", err)
+	}
+}
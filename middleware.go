@@ -0,0 +1,131 @@
+package firebase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Middleware wraps an Api to add behavior around its calls, such as
+// logging, metrics or caching. See Api for the contract wrappers must honor.
+//
+// Since an Api has no notion of headers or streamed bodies, middlewares only
+// see the calls going through the Api interface: reads such as Child, Get,
+// Children, Keys or GetMulti, and writes such as Set, Push, Update, Remove or
+// Batch. The following calls use the built-in client directly and are not
+// seen by middlewares: GetWithETag, SetIfMatch, SetIfAbsent, RemoveIfMatch,
+// RemoveAndGet and Transaction, which rely on ETags; CachedChild; CallFull
+// and CallStream; Export and Import; Watch; and reads decoded as they are
+// received with StreamDecode, which is ignored while middlewares are used.
+type Middleware func(Api) Api
+
+// Use wraps the calls made by f, and the references derived from it
+// afterwards, with the given middlewares, the first one being the outermost.
+// Middlewares added by a later Use are nested inside the ones added before.
+//
+//	f.Use(firebase.LoggingMiddleware(logger), firebase.RetryMiddleware(policy))
+//
+// Middlewares wrap the Api at the time of each call, so the built-in client
+// keeps following the fields of f. See Middleware for the calls they see.
+func (f *F) Use(mws ...Middleware) {
+	f.middlewares = append(append([]Middleware(nil), f.middlewares...), mws...)
+}
+
+// wrap returns api wrapped with the middlewares of f.
+func (f *F) wrap(api Api) Api {
+	for i := len(f.middlewares) - 1; i >= 0; i-- {
+		api = f.middlewares[i](api)
+	}
+
+	return api
+}
+
+// ApiFunc adapts a function to the Api and ContextApi interfaces, which
+// makes writing middlewares easier.
+type ApiFunc func(ctx context.Context, method, path, auth string, body []byte, params map[string]string) ([]byte, error)
+
+// Call calls fn with a background context.
+func (fn ApiFunc) Call(method, path, auth string, body []byte, params map[string]string) ([]byte, error) {
+	return fn(context.Background(), method, path, auth, body, params)
+}
+
+// CallContext calls fn.
+func (fn ApiFunc) CallContext(ctx context.Context, method, path, auth string, body []byte, params map[string]string) ([]byte, error) {
+	return fn(ctx, method, path, auth, body, params)
+}
+
+// CallContext calls api with ctx if it implements ContextApi, or else calls
+// it unless ctx is already done, as middlewares should do.
+func CallContext(ctx context.Context, api Api, method, path, auth string, body []byte, params map[string]string) ([]byte, error) {
+	if capi, ok := api.(ContextApi); ok {
+		return capi.CallContext(ctx, method, path, auth, body, params)
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return api.Call(method, path, auth, body, params)
+}
+
+// LoggingMiddleware logs every call made through the wrapped Api, along
+// with its error if it fails. The calls listed by Middleware as not going
+// through the Api are not logged by it, while the Logger of F receives the
+// requests of the built-in client whichever way they are made.
+func LoggingMiddleware(l Logger) Middleware {
+	return func(next Api) Api {
+		return ApiFunc(func(ctx context.Context, method, path, auth string, body []byte, params map[string]string) ([]byte, error) {
+			logf(l, "Calling %v %q\n", method, redact(path))
+
+			res, err := CallContext(ctx, next, method, path, auth, body, params)
+			if err != nil {
+				logf(l, "Call to %v %q failed: %v\n", method, redact(path), err)
+			}
+
+			return res, err
+		})
+	}
+}
+
+// RetryMiddleware retries the idempotent calls made through the wrapped Api
+// that fail with a 5xx status or a network error, like Retry does for the
+// built-in client. The calls listed by Middleware as not going through the
+// Api are not retried by it, while Retry applies to those made with the
+// built-in client as well.
+func RetryMiddleware(p *RetryPolicy) Middleware {
+	return func(next Api) Api {
+		return ApiFunc(func(ctx context.Context, method, path, auth string, body []byte, params map[string]string) ([]byte, error) {
+			for i := 0; ; i++ {
+				res, err := CallContext(ctx, next, method, path, auth, body, params)
+				if err == nil || !idempotent[method] || !retryable(err) || ctx.Err() != nil {
+					return res, err
+				}
+
+				if i >= p.MaxRetries {
+					return nil, fmt.Errorf("firebase: giving up after %d attempts: %w", i+1, err)
+				}
+
+				t := time.NewTimer(p.delay(i))
+				select {
+				case <-ctx.Done():
+					t.Stop()
+					return nil, ctx.Err()
+				case <-t.C:
+				}
+			}
+		})
+	}
+}
+
+// retryable reports whether a call failing with err may succeed if made
+// again: error statuses are only retried from 500 on, while other errors
+// are assumed to come from the network.
+func retryable(err error) bool {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode >= 500
+	}
+
+	return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded) && !errors.Is(err, ErrResponseTooLarge)
+}
@@ -0,0 +1,131 @@
+package firebase
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestUse(t *testing.T) {
+	var order []string
+
+	tag := func(name string) Middleware {
+		return func(next Api) Api {
+			return ApiFunc(func(ctx context.Context, method, path, auth string, body []byte, params map[string]string) ([]byte, error) {
+				order = append(order, name)
+				return CallContext(ctx, next, method, path, auth, body, params)
+			})
+		}
+	}
+
+	fake := NewFake()
+	fake.Seed("a", "value")
+
+	client := new(F)
+	client.Init("https://example.firebaseio.com", "", fake)
+	client.Use(tag("outer"), tag("inner"))
+
+	if v := client.Ref("a").Value(); v != "value" {
+		t.Fatalf("Expected the call to reach the Api, got %v\n", v)
+	}
+
+	if strings.Join(order, ",") != "outer,inner" {
+		t.Fatalf("Unexpected order %v\n", order)
+	}
+}
+
+func TestRetryMiddleware(t *testing.T) {
+	failures, attempts := 0, 0
+
+	fake := NewFake()
+	flaky := func(next Api) Api {
+		return ApiFunc(func(ctx context.Context, method, path, auth string, body []byte, params map[string]string) ([]byte, error) {
+			attempts++
+			if failures > 0 {
+				failures--
+				return nil, &APIError{StatusCode: http.StatusServiceUnavailable}
+			}
+			return CallContext(ctx, next, method, path, auth, body, params)
+		})
+	}
+
+	var buf bytes.Buffer
+
+	client := new(F)
+	client.Init("https://example.firebaseio.com", "", fake)
+	client.Use(LoggingMiddleware(log.New(&buf, "", 0)), RetryMiddleware(&RetryPolicy{MaxRetries: 2, BaseDelay: time.Millisecond}), flaky)
+
+	failures = 2
+	if _, err := client.Set("a", 1, nil); err != nil {
+		t.Fatalf("Expected the call to be retried, got %v\n", err)
+	}
+
+	failures = 3
+	if _, err := client.Set("a", 1, nil); !strings.Contains(fmt.Sprint(err), "giving up after 3 attempts") {
+		t.Fatalf("Expected the retries to be exhausted, got %v\n", err)
+	}
+
+	failures = 1
+	if _, err := client.Push(1, nil); err == nil {
+		t.Fatalf("Expected POST not to be retried\n")
+	}
+
+	attempts = 0
+	var apiErr *APIError
+	if _, err := client.ChildE("a", map[string]string{"orderBy": "bad"}, nil); !errors.As(err, &apiErr) || attempts != 1 {
+		t.Fatalf("Expected 4xx not to be retried, got %v after %d attempts\n", err, attempts)
+	}
+
+	if n := strings.Count(buf.String(), "Calling"); n != 4 {
+		t.Fatalf("Expected 4 calls to be logged, got %d:\n%s\n", n, buf.String())
+	}
+
+	if n := strings.Count(buf.String(), "failed"); n != 3 {
+		t.Fatalf("Expected 3 failures to be logged, got %d:\n%s\n", n, buf.String())
+	}
+}
+
+func TestUseBuiltinClient(t *testing.T) {
+	var requests []string
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Query().Get("auth")+r.Header.Get("Authorization"))
+		w.Header().Set("ETag", "etag")
+		w.Write([]byte(`1`))
+	}))
+	defer ts.Close()
+
+	var buf bytes.Buffer
+
+	client := new(F)
+	client.Init(ts.URL, "", nil)
+	client.Use(LoggingMiddleware(log.New(&buf, "", 0)))
+
+	// fields set after Use are still followed
+	client.TokenSource = StaticTokenSource("access")
+	client.BearerAuth = true
+
+	if v := client.AsUser("user").Child("a", nil, nil).Value(); v != float64(1) {
+		t.Fatalf("Unexpected value %v\n", v)
+	}
+
+	if err := client.Transaction("a", func(v interface{}) (interface{}, error) { return 2, nil }); err != nil {
+		t.Fatalf("Expected transactions to work with middlewares, got %v\n", err)
+	}
+
+	expected := []string{"GET user", "GET Bearer access", "PUT Bearer access"}
+	if fmt.Sprint(requests) != fmt.Sprint(expected) {
+		t.Fatalf("Expected %q, got %q\n", expected, requests)
+	}
+
+	if n := strings.Count(buf.String(), "Calling"); n != 1 {
+		t.Fatalf("Expected the call made through the Api to be logged, got:\n%s\n", buf.String())
+	}
+}
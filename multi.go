@@ -0,0 +1,122 @@
+package firebase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// DefaultConcurrency is the number of requests GetMulti makes at once when
+// Concurrency is not set.
+const DefaultConcurrency = 8
+
+// MultiError gathers the errors of the calls made by GetMulti.
+type MultiError []error
+
+// Error returns the messages of all the errors.
+func (e MultiError) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+
+	return strings.Join(msgs, "; ")
+}
+
+// Is reports whether any of the errors matches target, so that errors.Is
+// matches any of them.
+func (e MultiError) Is(target error) bool {
+	for _, err := range e {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// As finds the first of the errors that matches target, so that errors.As
+// matches any of them.
+func (e MultiError) As(target interface{}) bool {
+	for _, err := range e {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// GetMulti reads the values at the given paths concurrently, with up to
+// Concurrency goroutines each making one request at a time, and returns them
+// keyed by path.
+// Paths that could not be read are left out of the result and their errors
+// are returned together as a MultiError.
+func (f *F) GetMulti(paths []string, params map[string]string) (map[string]interface{}, error) {
+	return f.GetMultiContext(context.Background(), paths, params)
+}
+
+// GetMultiContext is like GetMulti but uses ctx for the underlying
+// requests. Paths not read yet when ctx is done are reported as failed.
+func (f *F) GetMultiContext(ctx context.Context, paths []string, params map[string]string) (map[string]interface{}, error) {
+	n := f.Concurrency
+	if n <= 0 {
+		n = DefaultConcurrency
+	}
+
+	if n > len(paths) {
+		n = len(paths)
+	}
+
+	var (
+		mu   sync.Mutex
+		wg   sync.WaitGroup
+		errs MultiError
+	)
+
+	ret := make(map[string]interface{}, len(paths))
+
+	todo := make(chan string, len(paths))
+	for _, p := range paths {
+		todo <- p
+	}
+	close(todo)
+
+	// each worker reads paths until none are left
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for p := range todo {
+				var v interface{}
+				var err error
+
+				if ctx.Err() != nil {
+					err = fmt.Errorf("firebase: get %s: %w", f.url(p), ctx.Err())
+				} else {
+					err = f.GetContext(ctx, p, params, &v)
+				}
+
+				mu.Lock()
+				if err != nil {
+					errs = append(errs, err)
+				} else {
+					ret[p] = v
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	if len(errs) > 0 {
+		return ret, errs
+	}
+
+	return ret, nil
+}
@@ -0,0 +1,86 @@
+package firebase
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestGetMulti(t *testing.T) {
+	var active, peak int32
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&active, 1)
+		defer atomic.AddInt32(&active, -1)
+
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+
+		time.Sleep(20 * time.Millisecond)
+
+		if r.URL.Path == "/missing.json" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(`"` + r.URL.Path + `"`))
+	}))
+	defer ts.Close()
+
+	client := new(F)
+	client.Init(ts.URL, "", nil)
+	client.Concurrency = 2
+
+	values, err := client.GetMulti([]string{"a", "b", "c", "missing"}, nil)
+
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Expected ErrNotFound, got %v\n", err)
+	}
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusNotFound {
+		t.Fatalf("Expected an *APIError, got %v\n", err)
+	}
+
+	if len(values) != 3 || values["b"] != "/b.json" {
+		t.Fatalf("Unexpected values %v\n", values)
+	}
+
+	if peak > 2 {
+		t.Fatalf("Expected at most 2 concurrent requests, got %d\n", peak)
+	}
+}
+
+func TestGetMultiCanceled(t *testing.T) {
+	calls := int32(0)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.Write([]byte(`null`))
+	}))
+	defer ts.Close()
+
+	client := new(F)
+	client.Init(ts.URL, "", nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	values, err := client.GetMultiContext(ctx, []string{"a", "b", "c"}, nil)
+
+	var errs MultiError
+	if !errors.As(err, &errs) || len(errs) != 3 || !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected every path to fail, got %v\n", err)
+	}
+
+	if len(values) != 0 || calls != 0 {
+		t.Fatalf("Expected no request, got %d and values %v\n", calls, values)
+	}
+}
@@ -0,0 +1,38 @@
+package firebase
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+)
+
+// Int64 converts a number decoded into an interface{} value, either a
+// json.Number when UseNumber is set or a float64 otherwise, to an int64.
+// An error is returned for other values and for numbers that are not
+// integers or do not fit.
+func Int64(v interface{}) (int64, error) {
+	switch n := v.(type) {
+	case json.Number:
+		return n.Int64()
+	case float64:
+		if n != math.Trunc(n) || n < math.MinInt64 || n >= math.MaxInt64 {
+			return 0, fmt.Errorf("firebase: %v is not an int64", n)
+		}
+		return int64(n), nil
+	}
+
+	return 0, fmt.Errorf("firebase: %v is not a number", v)
+}
+
+// Float64 converts a number decoded into an interface{} value, either a
+// json.Number when UseNumber is set or a float64 otherwise, to a float64.
+func Float64(v interface{}) (float64, error) {
+	switch n := v.(type) {
+	case json.Number:
+		return n.Float64()
+	case float64:
+		return n, nil
+	}
+
+	return 0, fmt.Errorf("firebase: %v is not a number", v)
+}
@@ -0,0 +1,51 @@
+package firebase
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestUseNumber(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"id":9007199254740993}`))
+	}))
+	defer ts.Close()
+
+	client := new(F)
+	client.Init(ts.URL, "", nil)
+	client.UseNumber = true
+
+	v := client.Child("user", nil, nil).Value().(map[string]interface{})
+
+	if v["id"] != json.Number("9007199254740993") {
+		t.Fatalf("Expected a json.Number, got %T %v\n", v["id"], v["id"])
+	}
+
+	if id, err := Int64(v["id"]); err != nil || id != 9007199254740993 {
+		t.Fatalf("Expected the exact id, got %d %v\n", id, err)
+	}
+}
+
+func TestInt64(t *testing.T) {
+	if n, err := Int64(float64(42)); err != nil || n != 42 {
+		t.Fatalf("Expected 42, got %d %v\n", n, err)
+	}
+
+	for _, v := range []interface{}{1.5, "42", json.Number("1.5"), nil} {
+		if _, err := Int64(v); err == nil {
+			t.Fatalf("Expected an error for %v\n", v)
+		}
+	}
+}
+
+func TestFloat64(t *testing.T) {
+	if f, err := Float64(json.Number("1.5")); err != nil || f != 1.5 {
+		t.Fatalf("Expected 1.5, got %v %v\n", f, err)
+	}
+
+	if _, err := Float64(true); err == nil {
+		t.Fatalf("Expected an error for a boolean\n")
+	}
+}
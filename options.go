@@ -0,0 +1,97 @@
+package firebase
+
+import (
+	"errors"
+	"net/http"
+	"time"
+)
+
+// Option configures a client created with NewClient.
+type Option func(*F) error
+
+// NewClient returns a client for the given root url, configured with the
+// given options. It is the preferred way of creating a client, since unlike
+// Init it cannot leave the client partially initialized.
+func NewClient(root string, opts ...Option) (*F, error) {
+	f := new(F)
+
+	err := f.Init(root, "", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, opt := range opts {
+		if err := opt(f); err != nil {
+			return nil, err
+		}
+	}
+
+	return f, nil
+}
+
+// WithAuth sets the authentication token sent with every call.
+func WithAuth(auth string) Option {
+	return func(f *F) error {
+		f.Auth = auth
+		return nil
+	}
+}
+
+// WithHTTPClient sets the HTTP client used to make calls.
+func WithHTTPClient(c *http.Client) Option {
+	return func(f *F) error {
+		if c == nil {
+			return errors.New("firebase: nil http client")
+		}
+
+		f.HTTPClient = c
+		return nil
+	}
+}
+
+// WithTransport sets the Transport of the HTTP client used to make calls,
+// e.g. to tune connection pooling with MaxIdleConnsPerHost for high
+// throughputs. A custom Transport only uses HTTP/2 if ForceAttemptHTTP2 is
+// set; cloning http.DefaultTransport keeps its defaults, including HTTP/2.
+// The Transport is set on a copy of HTTPClient, so that a client given to
+// WithHTTPClient, such as http.DefaultClient, is left unchanged.
+func WithTransport(t *http.Transport) Option {
+	return func(f *F) error {
+		if t == nil {
+			return errors.New("firebase: nil transport")
+		}
+
+		c := *f.HTTPClient
+		c.Transport = t
+		f.HTTPClient = &c
+		return nil
+	}
+}
+
+// WithTimeout sets the timeout of every call; zero means no timeout.
+func WithTimeout(d time.Duration) Option {
+	return func(f *F) error {
+		if d < 0 {
+			return errors.New("firebase: negative timeout")
+		}
+
+		f.Timeout = d
+		return nil
+	}
+}
+
+// WithLogger sets the logger receiving the log messages of the client.
+func WithLogger(l Logger) Option {
+	return func(f *F) error {
+		f.Logger = l
+		return nil
+	}
+}
+
+// WithApi sets a custom Api used to make calls, e.g. a mock for testing.
+func WithApi(api Api) Option {
+	return func(f *F) error {
+		f.api = api
+		return nil
+	}
+}
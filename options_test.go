@@ -0,0 +1,66 @@
+package firebase
+
+import (
+	"log"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestNewClient(t *testing.T) {
+	hc := new(http.Client)
+	logger := log.New(os.Stderr, "", 0)
+
+	client, err := NewClient("https://example.firebaseio.com/",
+		WithAuth("token"),
+		WithHTTPClient(hc),
+		WithTimeout(time.Second),
+		WithLogger(logger))
+
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+
+	if client.Url != "https://example.firebaseio.com" || client.Auth != "token" ||
+		client.HTTPClient != hc || client.Timeout != time.Second || client.Logger != logger {
+		t.Fatalf("Options were not applied: %+v\n", client)
+	}
+
+	if _, err := NewClient("not a url"); err == nil {
+		t.Fatalf("Expected an error for an invalid url\n")
+	}
+
+	if _, err := NewClient("https://example.firebaseio.com", WithTimeout(-1)); err == nil {
+		t.Fatalf("Expected an error for a negative timeout\n")
+	}
+}
+
+func TestWithTransport(t *testing.T) {
+	tr := http.DefaultTransport.(*http.Transport).Clone()
+	tr.MaxIdleConnsPerHost = 100
+
+	client, err := NewClient("https://example.firebaseio.com", WithTransport(tr))
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+
+	if client.HTTPClient.Transport != tr {
+		t.Fatalf("Expected the transport to be set\n")
+	}
+
+	hc := &http.Client{Timeout: time.Second}
+
+	client, err = NewClient("https://example.firebaseio.com", WithHTTPClient(hc), WithTransport(tr))
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+
+	if hc.Transport != nil || client.HTTPClient.Transport != tr || client.HTTPClient.Timeout != time.Second {
+		t.Fatalf("Expected the transport to be set on a copy of the given client\n")
+	}
+
+	if _, err := NewClient("https://example.firebaseio.com", WithTransport(nil)); err == nil {
+		t.Fatalf("Expected an error for a nil transport\n")
+	}
+}
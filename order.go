@@ -0,0 +1,146 @@
+package firebase
+
+import (
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// valueAt returns the value at the given keys of v, or nil if missing.
+func valueAt(v interface{}, keys []string) interface{} {
+	for _, k := range keys {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+
+		v = m[k]
+	}
+
+	return v
+}
+
+// orderValue returns the value kv is ordered by for the orderBy parameter
+// child, which is "$key", "$value" or the path of a child.
+func orderValue(child string, kv KV) interface{} {
+	switch child {
+	case "$key":
+		return kv.Key
+	case "$value":
+		return kv.Value
+	}
+
+	keys := strings.FieldsFunc(child, func(r rune) bool { return r == '/' })
+	return valueAt(kv.Value, keys)
+}
+
+// sortChildren sorts kvs like Firebase orders children for the orderBy
+// parameter child. Firebase leaves the order of the REST responses undefined,
+// so ordered results must be sorted by the client.
+func sortChildren(kvs []KV, child string) {
+	sort.SliceStable(kvs, func(i, j int) bool {
+		return compareChildren(child, kvs[i], kvs[j]) < 0
+	})
+}
+
+// compareChildren orders children by their value for the orderBy parameter
+// child, and by key when they are equal.
+func compareChildren(child string, a, b KV) int {
+	if child != "$key" {
+		if c := compareValues(orderValue(child, a), orderValue(child, b)); c != 0 {
+			return c
+		}
+	}
+
+	return compareKeys(a.Key, b.Key)
+}
+
+// compareKeys orders keys like Firebase: keys parsing as 32-bit integers
+// come first in numerical order, followed by the other keys in lexicographic
+// order.
+func compareKeys(a, b string) int {
+	ai, aErr := strconv.ParseInt(a, 10, 32)
+	bi, bErr := strconv.ParseInt(b, 10, 32)
+
+	switch {
+	case aErr == nil && bErr == nil:
+		return compareInts(ai, bi)
+	case aErr == nil:
+		return -1
+	case bErr == nil:
+		return 1
+	}
+
+	return strings.Compare(a, b)
+}
+
+// compareValues orders values like Firebase: null first, then false, true,
+// numbers in ascending order, strings in lexicographic order and objects.
+func compareValues(a, b interface{}) int {
+	if c := compareInts(int64(valueRank(a)), int64(valueRank(b))); c != 0 {
+		return c
+	}
+
+	if a, ok := number(a); ok {
+		b, _ := number(b)
+		if a < b {
+			return -1
+		} else if a > b {
+			return 1
+		}
+		return 0
+	}
+
+	if a, ok := a.(string); ok {
+		return strings.Compare(a, b.(string))
+	}
+
+	return 0
+}
+
+// number returns the value of v if it is a number, which is decoded as a
+// json.Number with UseNumber.
+func number(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case float64:
+		return v, true
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	}
+
+	return 0, false
+}
+
+// valueRank returns the rank of the type of v in the Firebase ordering.
+func valueRank(v interface{}) int {
+	switch v := v.(type) {
+	case nil:
+		return 0
+	case bool:
+		if v {
+			return 2
+		}
+		return 1
+	case float64, json.Number:
+		return 3
+	case string:
+		return 4
+	}
+
+	return 5
+}
+
+// compareInts returns -1, 0 or 1 when a is less than, equal to or greater
+// than b.
+func compareInts(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+
+	return 0
+}
@@ -0,0 +1,129 @@
+package firebase
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"strconv"
+)
+
+// Paginator reads the children matching a query one page at a time.
+// It is created by Paginate and is not safe for concurrent use.
+type Paginator struct {
+	f      *F
+	params map[string]string
+	size   int
+	err    error
+
+	// cursor is the first child of the next page, fetched as the extra
+	// child of the previous one, and skip the number of children ordered
+	// before it with the same value, which were already returned.
+	cursor *KV
+	skip   int
+	done   bool
+}
+
+// Paginate returns a Paginator over the immediate children of f matching q,
+// in pages of pageSize children. Children are ordered by key unless q is
+// ordered, and the limits of q are ignored. q may be nil to page through all
+// the children.
+//
+//	p := f.Paginate(firebase.NewQuery().OrderByChild("age"), 100)
+//	for {
+//		page, err := p.Next(ctx)
+//		if err == io.EOF {
+//			break
+//		}
+//		...
+//	}
+func (f *F) Paginate(q *Query, pageSize int) *Paginator {
+	p := &Paginator{f: f, size: pageSize}
+
+	if q != nil {
+		p.err = q.Err()
+		p.params = q.Params()
+	} else {
+		p.params = map[string]string{}
+	}
+
+	if pageSize <= 0 {
+		p.err = errors.New("firebase: page size must be positive")
+	}
+
+	if _, ok := p.params["orderBy"]; !ok {
+		p.params["orderBy"] = `"$key"`
+	}
+	delete(p.params, "limitToFirst")
+	delete(p.params, "limitToLast")
+
+	return p
+}
+
+// Next returns the next page of children, or io.EOF once all of them have
+// been returned. The last page may be shorter than the page size.
+func (p *Paginator) Next(ctx context.Context) ([]KV, error) {
+	if p.err != nil {
+		return nil, p.err
+	}
+	if p.done {
+		return nil, io.EOF
+	}
+
+	var child string
+	if err := json.Unmarshal([]byte(p.params["orderBy"]), &child); err != nil {
+		p.err = err
+		return nil, err
+	}
+
+	// fetch one more child than needed to use it as the next cursor
+	params := make(map[string]string, len(p.params)+2)
+	for k, v := range p.params {
+		params[k] = v
+	}
+	params["limitToFirst"] = strconv.Itoa(p.skip + p.size + 1)
+
+	if p.cursor != nil {
+		start, err := json.Marshal(orderValue(child, *p.cursor))
+		if err != nil {
+			p.err = err
+			return nil, err
+		}
+		params["startAt"] = string(start)
+	}
+
+	kvs, err := p.f.orderedChildren(ctx, p.f.Url, params)
+	if err != nil {
+		return nil, err
+	}
+
+	// children with the same value as the cursor are returned again
+	if p.skip > len(kvs) {
+		p.skip = len(kvs)
+	}
+	kvs = kvs[p.skip:]
+
+	if len(kvs) <= p.size {
+		p.done = true
+		if len(kvs) == 0 {
+			return nil, io.EOF
+		}
+		return kvs, nil
+	}
+
+	page, next := kvs[:p.size], kvs[p.size]
+
+	skip := 0
+	if p.cursor != nil && compareValues(orderValue(child, *p.cursor), orderValue(child, next)) == 0 {
+		skip = p.skip
+	}
+	for _, kv := range page {
+		if child != "$key" && compareValues(orderValue(child, kv), orderValue(child, next)) == 0 {
+			skip++
+		}
+	}
+
+	p.cursor, p.skip = &next, skip
+
+	return page, nil
+}
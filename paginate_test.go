@@ -0,0 +1,131 @@
+package firebase
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestPaginate(t *testing.T) {
+	fake := NewFake()
+	fake.Seed("scores", map[string]int{"a": 3, "b": 1, "c": 2, "d": 2, "e": 2, "f": 5, "g": 4})
+	fake.Seed("users", map[string]interface{}{
+		"a": map[string]int{"age": 1}, "b": map[string]int{"age": 2}, "c": map[string]int{"age": 3},
+		"d": map[string]int{"age": 4}, "e": map[string]int{"age": 5},
+	})
+
+	for _, test := range []struct {
+		path      string
+		q         *Query
+		size      int
+		useNumber bool
+		pages     [][]string
+	}{
+		{"/scores", nil, 3, false, [][]string{{"a", "b", "c"}, {"d", "e", "f"}, {"g"}}},
+		{"/scores", nil, 7, false, [][]string{{"a", "b", "c", "d", "e", "f", "g"}}},
+		{"/scores", NewQuery().OrderByKey().StartAt("c"), 2, false, [][]string{{"c", "d"}, {"e", "f"}, {"g"}}},
+		// the ties on 2 span pages
+		{"/scores", NewQuery().OrderByValue(), 2, false, [][]string{{"b", "c"}, {"d", "e"}, {"a", "g"}, {"f"}}},
+		{"/scores", NewQuery().OrderByValue(), 1, false, [][]string{{"b"}, {"c"}, {"d"}, {"e"}, {"a"}, {"g"}, {"f"}}},
+		// numbers are decoded as json.Number
+		{"/scores", NewQuery().OrderByValue(), 2, true, [][]string{{"b", "c"}, {"d", "e"}, {"a", "g"}, {"f"}}},
+		{"/users", NewQuery().OrderByChild("age"), 2, true, [][]string{{"a", "b"}, {"c", "d"}, {"e"}}},
+	} {
+		client := new(F)
+		client.Init("https://example.firebaseio.com"+test.path, "", fake)
+		client.UseNumber = test.useNumber
+
+		p := client.Paginate(test.q, test.size)
+
+		var pages [][]string
+		for {
+			page, err := p.Next(context.Background())
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatalf("%v\n", err)
+			}
+
+			var keys []string
+			for _, kv := range page {
+				keys = append(keys, kv.Key)
+			}
+			pages = append(pages, keys)
+		}
+
+		if !reflect.DeepEqual(pages, test.pages) {
+			t.Fatalf("Expected pages %v, got %v\n", test.pages, pages)
+		}
+
+		if _, err := p.Next(context.Background()); err != io.EOF {
+			t.Fatalf("Expected io.EOF once exhausted, got %v\n", err)
+		}
+	}
+}
+
+func TestPaginateUnordered(t *testing.T) {
+	keys := []string{"a", "b", "c", "d", "e"}
+
+	// the server answers with the right children, in reverse order
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var start string
+		json.Unmarshal([]byte(r.URL.Query().Get("startAt")), &start)
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limitToFirst"))
+
+		var children []string
+		for i, k := range keys {
+			if k >= start && len(children) < limit {
+				children = append([]string{`"` + k + `":` + strconv.Itoa(i)}, children...)
+			}
+		}
+
+		w.Write([]byte("{" + strings.Join(children, ",") + "}"))
+	}))
+	defer ts.Close()
+
+	client := new(F)
+	client.Init(ts.URL+"/scores", "", nil)
+
+	p := client.Paginate(NewQuery().OrderByKey(), 2)
+
+	var pages [][]string
+	for {
+		page, err := p.Next(context.Background())
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("%v\n", err)
+		}
+
+		var keys []string
+		for _, kv := range page {
+			keys = append(keys, kv.Key)
+		}
+		pages = append(pages, keys)
+	}
+
+	if expected := [][]string{{"a", "b"}, {"c", "d"}, {"e"}}; !reflect.DeepEqual(pages, expected) {
+		t.Fatalf("Expected pages %v, got %v\n", expected, pages)
+	}
+}
+
+func TestPaginateEmpty(t *testing.T) {
+	client := new(F)
+	client.Init("https://example.firebaseio.com/scores", "", NewFake())
+
+	if _, err := client.Paginate(nil, 10).Next(context.Background()); err != io.EOF {
+		t.Fatalf("Expected io.EOF, got %v\n", err)
+	}
+
+	if _, err := client.Paginate(nil, 0).Next(context.Background()); err == nil || err == io.EOF {
+		t.Fatalf("Expected an error for an invalid page size, got %v\n", err)
+	}
+}
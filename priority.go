@@ -0,0 +1,33 @@
+package firebase
+
+// SetPriority sets the priority of the data at the given path, which is used
+// to order children with orderBy="$priority". priority must be a string, a
+// number or nil to remove it.
+func (f *F) SetPriority(path string, priority interface{}) error {
+	_, err := f.Set(path+"/.priority", priority, map[string]string{"print": "silent"})
+
+	return err
+}
+
+// SetWithPriority is like Set but also sets the priority of the value.
+func (f *F) SetWithPriority(path string, value, priority interface{}, params map[string]string) (*F, error) {
+	v := map[string]interface{}{
+		".value":    value,
+		".priority": priority,
+	}
+
+	return f.Set(path, v, params)
+}
+
+// Priority returns the priority of the data at the given path, or nil if
+// it has none.
+func (f *F) Priority(path string) (interface{}, error) {
+	var priority interface{}
+
+	_, err := f.ChildE(path+"/.priority", nil, &priority)
+	if err != nil {
+		return nil, err
+	}
+
+	return priority, nil
+}
@@ -0,0 +1,52 @@
+package firebase
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPriority(t *testing.T) {
+	var priority, body interface{}
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/users/jack/.priority.json" && r.Method == "PUT":
+			json.NewDecoder(r.Body).Decode(&priority)
+			w.WriteHeader(http.StatusNoContent)
+		case r.URL.Path == "/users/jack/.priority.json":
+			json.NewEncoder(w).Encode(priority)
+		default:
+			json.NewDecoder(r.Body).Decode(&body)
+			json.NewEncoder(w).Encode(body)
+		}
+	}))
+	defer ts.Close()
+
+	client := new(F)
+	client.Init(ts.URL, "", nil)
+
+	if err := client.SetPriority("users/jack", 10); err != nil {
+		t.Fatalf("%v\n", err)
+	}
+
+	p, err := client.Priority("users/jack")
+
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+
+	if p != float64(10) {
+		t.Fatalf("Expected priority 10, got %v\n", p)
+	}
+
+	if _, err := client.SetWithPriority("users/john", "John", "a", nil); err != nil {
+		t.Fatalf("%v\n", err)
+	}
+
+	m := body.(map[string]interface{})
+	if m[".value"] != "John" || m[".priority"] != "a" {
+		t.Fatalf("Unexpected body %v\n", body)
+	}
+}
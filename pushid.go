@@ -0,0 +1,74 @@
+package firebase
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// pushChars are the characters of push IDs, in ASCII order so that IDs sort
+// like the time they encode.
+const pushChars = "-0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZ_abcdefghijklmnopqrstuvwxyz"
+
+// pushID holds the state of NewPushID.
+var pushID struct {
+	// mu guards the fields below
+	mu sync.Mutex
+
+	// last is the time of the last generated ID, in milliseconds
+	last int64
+
+	// rand is the random part of the last generated ID
+	rand [12]int
+}
+
+// NewPushID returns a key generated like Firebase does for Push, so that the
+// key of a new child can be known before writing it, e.g. to reference it in
+// a multi-location update. IDs are 20 characters long: 8 characters encoding
+// the time in milliseconds followed by 12 random characters, taken from
+// "-0-9A-Z_a-z". Within the same millisecond, the random part is incremented
+// instead, so that IDs generated by a process sort in creation order.
+func NewPushID() string {
+	pushID.mu.Lock()
+	defer pushID.mu.Unlock()
+
+	now := time.Now().UnixNano() / int64(time.Millisecond)
+
+	if now == pushID.last {
+		for i := len(pushID.rand) - 1; i >= 0; i-- {
+			if pushID.rand[i] < len(pushChars)-1 {
+				pushID.rand[i]++
+				break
+			}
+			pushID.rand[i] = 0
+		}
+	} else {
+		for i := range pushID.rand {
+			pushID.rand[i] = rand.Intn(len(pushChars))
+		}
+	}
+	pushID.last = now
+
+	var b [20]byte
+
+	for i := 7; i >= 0; i-- {
+		b[i] = pushChars[now%int64(len(pushChars))]
+		now /= int64(len(pushChars))
+	}
+
+	for i, r := range pushID.rand {
+		b[8+i] = pushChars[r]
+	}
+
+	return string(b[:])
+}
+
+// PushIdempotent is like Push but generates the key of the new child with
+// NewPushID and writes it with a PUT, instead of a POST which makes Firebase
+// generate the key. Since writing the same value at the same key twice has
+// no further effect, the write is retried like Set with Retry, without the
+// risk of creating duplicate children. Keys are generated from the local
+// clock rather than the server's.
+func (f *F) PushIdempotent(value interface{}, params map[string]string) (*F, error) {
+	return f.Set(NewPushID(), value, params)
+}
@@ -0,0 +1,69 @@
+package firebase
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewPushID(t *testing.T) {
+	ids := make([]string, 1000)
+	for i := range ids {
+		ids[i] = NewPushID()
+	}
+
+	if !sort.StringsAreSorted(ids) {
+		t.Fatalf("Expected push IDs to be sorted in creation order\n")
+	}
+
+	seen := map[string]bool{}
+
+	for _, id := range ids {
+		if len(id) != 20 || seen[id] {
+			t.Fatalf("Expected unique 20 character IDs, got %q\n", id)
+		}
+		seen[id] = true
+
+		for _, c := range id {
+			if !strings.ContainsRune(pushChars, c) {
+				t.Fatalf("Unexpected character %q in %q\n", c, id)
+			}
+		}
+	}
+}
+
+func TestPushIdempotent(t *testing.T) {
+	var paths []string
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.Method+" "+r.URL.Path)
+		if len(paths) == 1 {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		io.Copy(w, r.Body)
+	}))
+	defer ts.Close()
+
+	client := new(F)
+	client.Init(ts.URL+"/messages", "", nil)
+	client.Retry = &RetryPolicy{MaxRetries: 2, BaseDelay: time.Millisecond}
+
+	r, err := client.PushIdempotent("hello", nil)
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+
+	if len(r.Key()) != 20 || r.Value() != "hello" {
+		t.Fatalf("Unexpected child %s with value %v\n", r.Key(), r.Value())
+	}
+
+	expected := "PUT /messages/" + r.Key() + ".json"
+	if len(paths) != 2 || paths[0] != expected || paths[1] != expected {
+		t.Fatalf("Expected the same key to be retried with %q, got %q\n", expected, paths)
+	}
+}
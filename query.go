@@ -0,0 +1,215 @@
+package firebase
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Query builds the query parameters used to filter and order data.
+// Values are JSON-encoded as Firebase requires, so that strings end up
+// quoted, e.g. orderBy="name". Mistakes such as ordering twice are reported
+// by Err, which the methods taking a *Query check before any request:
+//
+//	q := firebase.NewQuery().OrderByChild("age").StartAt(18).LimitToFirst(10)
+//	if err := q.Err(); err != nil {
+//		return err
+//	}
+//	users := f.Child("users", q.Params(), nil)
+type Query struct {
+	params map[string]string
+	err    error
+}
+
+// NewQuery returns an empty query.
+func NewQuery() *Query {
+	return &Query{params: map[string]string{}}
+}
+
+// OrderByChild orders the results by the value of the given child key.
+// A query can only be ordered once; see Err.
+func (q *Query) OrderByChild(child string) *Query {
+	return q.orderBy(child)
+}
+
+// OrderByKey orders the results by their keys, which combined with StartAt
+// and EndAt allows paginating by key ranges.
+func (q *Query) OrderByKey() *Query {
+	return q.orderBy("$key")
+}
+
+// OrderByValue orders the results by their values.
+func (q *Query) OrderByValue() *Query {
+	return q.orderBy("$value")
+}
+
+// orderBy sets the ordering of the query, which Firebase only accepts once.
+func (q *Query) orderBy(by string) *Query {
+	if _, ok := q.params["orderBy"]; ok && q.err == nil {
+		q.err = errors.New("firebase: query cannot be ordered more than once")
+	}
+
+	return q.set("orderBy", by)
+}
+
+// LimitToFirst limits the results to the first n items of the ordering.
+func (q *Query) LimitToFirst(n int) *Query {
+	q.init()
+	q.params["limitToFirst"] = strconv.Itoa(n)
+	return q
+}
+
+// LimitToLast limits the results to the last n items of the ordering.
+func (q *Query) LimitToLast(n int) *Query {
+	q.init()
+	q.params["limitToLast"] = strconv.Itoa(n)
+	return q
+}
+
+// StartAt restricts the results to items at or after v in the ordering.
+// v must be a string, a number, a boolean or nil.
+func (q *Query) StartAt(v interface{}) *Query {
+	return q.set("startAt", v)
+}
+
+// EndAt restricts the results to items at or before v in the ordering.
+// v must be a string, a number, a boolean or nil.
+func (q *Query) EndAt(v interface{}) *Query {
+	return q.set("endAt", v)
+}
+
+// EqualTo restricts the results to items equal to v in the ordering.
+// v must be a string, a number, a boolean or nil.
+func (q *Query) EqualTo(v interface{}) *Query {
+	return q.set("equalTo", v)
+}
+
+// Shallow limits the results to the immediate children, each mapped to true.
+// It cannot be combined with the other query parameters.
+func (q *Query) Shallow() *Query {
+	q.init()
+	q.params["shallow"] = "true"
+	return q
+}
+
+// Shallow returns a new query that only lists the immediate children.
+func Shallow() *Query {
+	return NewQuery().Shallow()
+}
+
+// Pretty asks Firebase to pretty-print the returned JSON, which is mostly
+// useful together with RawChild when logging responses.
+func (q *Query) Pretty() *Query {
+	q.init()
+	q.params["print"] = "pretty"
+	return q
+}
+
+// FormatExport asks Firebase to include the priorities of the values read,
+// with leaves that have one returned as {".value": v, ".priority": p}.
+// Data read this way can be written back as is, priorities included.
+func (q *Query) FormatExport() *Query {
+	q.init()
+	q.params["format"] = "export"
+	return q
+}
+
+// SizeLimit is the maximum size of a write, expressed as the time Firebase
+// may take to process it. Writes estimated to exceed it are rejected with 412
+// Precondition Failed instead of blocking the database.
+type SizeLimit string
+
+// The size limits accepted by Firebase.
+const (
+	SizeLimitTiny      SizeLimit = "tiny"   // 1 second
+	SizeLimitSmall     SizeLimit = "small"  // 10 seconds
+	SizeLimitMedium    SizeLimit = "medium" // 30 seconds
+	SizeLimitLarge     SizeLimit = "large"  // 60 seconds
+	SizeLimitUnlimited SizeLimit = "unlimited"
+)
+
+// WriteSizeLimit sets the maximum size of the writes made with the query
+// parameters, such as with Set or Remove.
+func (q *Query) WriteSizeLimit(l SizeLimit) *Query {
+	q.init()
+	q.params["writeSizeLimit"] = string(l)
+	return q
+}
+
+// MaxTimeout is the longest timeout Firebase accepts for a read.
+const MaxTimeout = 15 * time.Minute
+
+// Timeout limits the time Firebase spends on reads made with the query
+// parameters, which fail with an error instead of running longer. It is
+// sent as a number of milliseconds, seconds or minutes, e.g. 3s, and must
+// be between 1ms and MaxTimeout. Other durations are reported by Err and
+// left out of Params, so reads using the parameters without checking Err run
+// without a server timeout. Durations are rounded up to the millisecond. It bounds the work of the server, while the Timeout of F
+// and contexts bound the time the client waits.
+func (q *Query) Timeout(d time.Duration) *Query {
+	q.init()
+
+	if d <= 0 || d > MaxTimeout {
+		if q.err == nil {
+			q.err = fmt.Errorf("firebase: query timeout %v is not between 1ms and %v", d, MaxTimeout)
+		}
+		return q
+	}
+
+	var s string
+	switch {
+	case d%time.Minute == 0:
+		s = strconv.FormatInt(int64(d/time.Minute), 10) + "min"
+	case d%time.Second == 0:
+		s = strconv.FormatInt(int64(d/time.Second), 10) + "s"
+	default:
+		s = strconv.FormatInt(int64((d+time.Millisecond-1)/time.Millisecond), 10) + "ms"
+	}
+
+	q.params["timeout"] = s
+	return q
+}
+
+// Params returns the query parameters to pass to the methods of F.
+// The returned map is a copy and can be modified freely. Params does not
+// report the errors of the query: when q may be invalid, check Err before
+// using them, or the parameters set last win, e.g. the last orderBy.
+func (q *Query) Params() map[string]string {
+	params := make(map[string]string, len(q.params))
+	for k, v := range q.params {
+		params[k] = v
+	}
+
+	return params
+}
+
+// Err returns the first error encountered while building the query.
+// OrderedChildren and Paginate fail with it without making a request.
+func (q *Query) Err() error {
+	return q.err
+}
+
+// init allocates the parameters of a query created without NewQuery.
+func (q *Query) init() {
+	if q.params == nil {
+		q.params = map[string]string{}
+	}
+}
+
+// set stores the JSON encoding of v under the given parameter.
+func (q *Query) set(param string, v interface{}) *Query {
+	q.init()
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		if q.err == nil {
+			q.err = err
+		}
+		return q
+	}
+
+	q.params[param] = string(b)
+	return q
+}
@@ -0,0 +1,101 @@
+package firebase
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestQueryParams(t *testing.T) {
+	p := NewQuery().OrderByChild("name").StartAt("A").EndAt(10).LimitToFirst(5).Params()
+
+	expected := map[string]string{
+		"orderBy":      `"name"`,
+		"startAt":      `"A"`,
+		"endAt":        `10`,
+		"limitToFirst": `5`,
+	}
+
+	if len(p) != len(expected) {
+		t.Fatalf("Expected %v, got %v\n", expected, p)
+	}
+
+	for k, v := range expected {
+		if p[k] != v {
+			t.Fatalf("Expected %s=%s, got %s\n", k, v, p[k])
+		}
+	}
+}
+
+func TestQueryOrderBySpecial(t *testing.T) {
+	if p := NewQuery().OrderByKey().Params(); p["orderBy"] != `"$key"` {
+		t.Fatalf("Unexpected orderBy for keys: %s\n", p["orderBy"])
+	}
+
+	if p := NewQuery().OrderByValue().Params(); p["orderBy"] != `"$value"` {
+		t.Fatalf("Unexpected orderBy for values: %s\n", p["orderBy"])
+	}
+}
+
+func TestQueryWriteSizeLimit(t *testing.T) {
+	if p := NewQuery().WriteSizeLimit(SizeLimitTiny).Params(); p["writeSizeLimit"] != "tiny" {
+		t.Fatalf("Unexpected writeSizeLimit: %s\n", p["writeSizeLimit"])
+	}
+}
+
+func TestQueryTimeout(t *testing.T) {
+	for d, want := range map[time.Duration]string{
+		3 * time.Second:         "3s",
+		1500 * time.Millisecond: "1500ms",
+		time.Microsecond:        "1ms",
+		2 * time.Minute:         "2min",
+		90 * time.Second:        "90s",
+		MaxTimeout:              "15min",
+	} {
+		if p := NewQuery().Timeout(d).Params(); p["timeout"] != want {
+			t.Errorf("Expected timeout %s for %v, got %s\n", want, d, p["timeout"])
+		}
+	}
+
+	for _, d := range []time.Duration{0, -time.Second, MaxTimeout + time.Millisecond} {
+		if err := NewQuery().Timeout(d).Err(); err == nil {
+			t.Errorf("Expected an error for %v\n", d)
+		}
+	}
+
+	client := new(F)
+	client.Init("https://example.firebaseio.com", "", NewFake())
+
+	q := NewQuery().OrderByKey().Timeout(MaxTimeout + time.Second)
+	if _, err := client.OrderedChildren("users", q); err == nil || err != q.Err() {
+		t.Fatalf("Expected OrderedChildren to fail with the timeout error, got %v\n", err)
+	}
+}
+
+func TestQueryFormatExport(t *testing.T) {
+	if p := NewQuery().FormatExport().Params(); p["format"] != "export" {
+		t.Fatalf("Unexpected format: %s\n", p["format"])
+	}
+}
+
+func TestQueryOrderByOnce(t *testing.T) {
+	if err := NewQuery().OrderByKey().StartAt("a").Err(); err != nil {
+		t.Fatalf("%v\n", err)
+	}
+
+	q := NewQuery().OrderByChild("age").OrderByKey()
+	if q.Err() == nil {
+		t.Fatalf("Expected an error for multiple orderBy\n")
+	}
+
+	client := new(F)
+	client.Init("https://example.firebaseio.com", "", NewFake())
+
+	if _, err := client.OrderedChildren("users", q); err != q.Err() {
+		t.Fatalf("Expected OrderedChildren to fail with %v, got %v\n", q.Err(), err)
+	}
+
+	if _, err := client.Paginate(q, 10).Next(context.Background()); err != q.Err() {
+		t.Fatalf("Expected Paginate to fail with %v, got %v\n", q.Err(), err)
+	}
+}
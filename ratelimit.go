@@ -0,0 +1,110 @@
+package firebase
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// RateLimiter limits the rate of requests made by a client with a token
+// bucket, to smooth bursts of calls and stay under Firebase quotas.
+// A RateLimiter can be shared by several clients to limit them as a whole.
+type RateLimiter struct {
+	// limit is the number of requests allowed per second
+	limit float64
+
+	// burst is the number of requests that can be made at once
+	burst int
+
+	// mu guards tokens and last
+	mu sync.Mutex
+
+	// tokens is the number of requests currently allowed, negative when
+	// requests are waiting
+	tokens float64
+
+	// last is the time tokens was last updated
+	last time.Time
+}
+
+// NewRateLimiter returns a limiter allowing limit requests per second on
+// average, with bursts of up to burst requests. An error is returned if limit
+// is not positive, as nothing would ever be allowed.
+func NewRateLimiter(limit float64, burst int) (*RateLimiter, error) {
+	if !(limit > 0) {
+		return nil, fmt.Errorf("firebase: rate limit %v is not positive", limit)
+	}
+
+	if burst < 1 {
+		burst = 1
+	}
+
+	return &RateLimiter{limit: limit, burst: burst, tokens: float64(burst)}, nil
+}
+
+// Limit returns the number of requests allowed per second.
+func (l *RateLimiter) Limit() float64 {
+	return l.limit
+}
+
+// Burst returns the number of requests that can be made at once.
+func (l *RateLimiter) Burst() int {
+	return l.burst
+}
+
+// Wait blocks until a request is allowed or ctx is done. It returns
+// ErrRateLimited without waiting if the request would not be allowed before
+// the deadline of ctx.
+func (l *RateLimiter) Wait(ctx context.Context) error {
+	now := time.Now()
+
+	d := l.reserve(now)
+	if d == 0 {
+		return nil
+	}
+
+	if deadline, ok := ctx.Deadline(); ok && now.Add(d).After(deadline) {
+		l.cancel()
+		return ErrRateLimited
+	}
+
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		l.cancel()
+		return ctx.Err()
+	}
+}
+
+// reserve takes a token and returns how long to wait before it is available.
+func (l *RateLimiter) reserve(now time.Time) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if !l.last.IsZero() {
+		l.tokens += now.Sub(l.last).Seconds() * l.limit
+		if l.tokens > float64(l.burst) {
+			l.tokens = float64(l.burst)
+		}
+	}
+	l.last = now
+
+	l.tokens--
+	if l.tokens >= 0 {
+		return 0
+	}
+
+	return time.Duration(-l.tokens / l.limit * float64(time.Second))
+}
+
+// cancel gives back a token taken by a request that was not made.
+func (l *RateLimiter) cancel() {
+	l.mu.Lock()
+	l.tokens++
+	l.mu.Unlock()
+}
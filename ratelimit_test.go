@@ -0,0 +1,83 @@
+package firebase
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRateLimiterWait(t *testing.T) {
+	l, err := NewRateLimiter(20, 2)
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+
+	start := time.Now()
+
+	for i := 0; i < 4; i++ {
+		if err := l.Wait(context.Background()); err != nil {
+			t.Fatalf("%v\n", err)
+		}
+	}
+
+	// the burst is allowed at once, then one request every 50ms
+	if d := time.Since(start); d < 90*time.Millisecond {
+		t.Fatalf("Expected requests to be delayed, took %v\n", d)
+	}
+}
+
+func TestRateLimiterInvalid(t *testing.T) {
+	for _, limit := range []float64{0, -1} {
+		if _, err := NewRateLimiter(limit, 1); err == nil {
+			t.Errorf("Expected an error for a limit of %v\n", limit)
+		}
+	}
+}
+
+func TestRateLimiterDeadline(t *testing.T) {
+	l, err := NewRateLimiter(1, 1)
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+
+	if err := l.Wait(context.Background()); err != nil {
+		t.Fatalf("%v\n", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if err := l.Wait(ctx); !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("Expected ErrRateLimited, got %v\n", err)
+	}
+}
+
+func TestRateLimitedClient(t *testing.T) {
+	calls := 0
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Write([]byte(`null`))
+	}))
+	defer ts.Close()
+
+	client := new(F)
+	client.Init(ts.URL, "", nil)
+	client.Timeout = 100 * time.Millisecond
+	client.RateLimiter, _ = NewRateLimiter(1, 1)
+
+	if _, err := client.ChildE("users", nil, nil); err != nil {
+		t.Fatalf("%v\n", err)
+	}
+
+	if _, err := client.ChildE("users", nil, nil); !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("Expected ErrRateLimited, got %v\n", err)
+	}
+
+	if calls != 1 {
+		t.Fatalf("Expected a single request, got %d\n", calls)
+	}
+}
@@ -0,0 +1,76 @@
+package firebase
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+// RetryPolicy configures how failed calls are retried with exponential backoff.
+type RetryPolicy struct {
+	// MaxRetries is the maximum number of retries after the first attempt.
+	MaxRetries int
+
+	// BaseDelay is the delay before the first retry. It doubles on every
+	// subsequent retry, with some random jitter applied.
+	BaseDelay time.Duration
+
+	// MaxDelay caps the delay between two attempts. Zero means no cap.
+	MaxDelay time.Duration
+}
+
+// idempotent lists the methods that can safely be sent more than once.
+var idempotent = map[string]bool{
+	"GET":    true,
+	"PUT":    true,
+	"PATCH":  true,
+	"DELETE": true,
+}
+
+// delay returns the backoff before the given retry, starting at zero.
+func (p *RetryPolicy) delay(retry int) time.Duration {
+	d := p.BaseDelay << uint(retry)
+	if d <= 0 || (p.MaxDelay > 0 && d > p.MaxDelay) {
+		d = p.MaxDelay
+	}
+
+	if d <= 0 {
+		return 0
+	}
+
+	// full jitter on the upper half spreads out clients retrying together
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+// retry runs attempt until it succeeds, fails permanently or the retry
+// policy is exhausted. The final error wraps the last underlying error.
+func (c *client) retry(ctx context.Context, method string, attempt func() (*Response, bool, error)) (*Response, error) {
+	p := c.retryPolicy
+	if p == nil || !idempotent[method] {
+		ret, _, err := attempt()
+		return ret, err
+	}
+
+	for i := 0; ; i++ {
+		ret, retryable, err := attempt()
+		if err == nil || !retryable {
+			return ret, err
+		}
+
+		if i >= p.MaxRetries {
+			return nil, fmt.Errorf("firebase: giving up after %d attempts: %w", i+1, err)
+		}
+
+		d := p.delay(i)
+		logf(c.logger, "Retrying %v in %v after error: %v\n", method, d, err)
+
+		t := time.NewTimer(d)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return nil, ctx.Err()
+		case <-t.C:
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package firebase
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRetry(t *testing.T) {
+	var calls int
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`"ok"`))
+	}))
+	defer ts.Close()
+
+	client := new(F)
+	client.Init(ts.URL, "", nil)
+	client.Retry = &RetryPolicy{MaxRetries: 3, BaseDelay: time.Millisecond}
+
+	r := client.Child("status", nil, nil)
+
+	if r == nil {
+		t.Fatalf("No child returned from the server\n")
+	}
+
+	if calls != 3 {
+		t.Fatalf("Expected 3 calls, got %d\n", calls)
+	}
+}
+
+func TestRetrySkipsPush(t *testing.T) {
+	var calls int
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	client := new(F)
+	client.Init(ts.URL, "", nil)
+	client.Retry = &RetryPolicy{MaxRetries: 3, BaseDelay: time.Millisecond}
+
+	_, err := client.Push("value", nil)
+
+	if err == nil {
+		t.Fatalf("Expected an error from the server\n")
+	}
+
+	if calls != 1 {
+		t.Fatalf("Expected 1 call, got %d\n", calls)
+	}
+}
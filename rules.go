@@ -0,0 +1,39 @@
+package firebase
+
+import (
+	"context"
+	"fmt"
+)
+
+// rulesPath is the location of the security rules relative to the root of
+// the database.
+const rulesPath = ".settings/rules"
+
+// GetRules returns the security rules of the database, whatever the current
+// Url or the path given to Init. The rules are returned as is, since they may contain comments and are
+// thus not strictly JSON. Reading the rules requires an admin credential,
+// such as a database secret or a service account token.
+func (f *F) GetRules() ([]byte, error) {
+	u := f.databaseRoot().url(rulesPath)
+
+	res, err := f.call(context.Background(), "GET", u, nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("firebase: get rules %s: %w", u, err)
+	}
+
+	return res, nil
+}
+
+// SetRules replaces the security rules of the database, e.g. to deploy them
+// from CI. The rules are sent as is, comments included, and validated by
+// Firebase. Like GetRules, it requires an admin credential.
+func (f *F) SetRules(rules []byte) error {
+	u := f.databaseRoot().url(rulesPath)
+
+	_, err := f.call(context.Background(), "PUT", u, rules, nil)
+	if err != nil {
+		return fmt.Errorf("firebase: set rules %s: %w", u, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,50 @@
+package firebase
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRules(t *testing.T) {
+	rules := "{\n  // only admins\n  \"rules\": {\".read\": false}\n}"
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/.settings/rules.json" || r.URL.Query().Get("auth") != "secret" {
+			t.Errorf("Unexpected request %s\n", r.URL)
+		}
+
+		if r.Method == "PUT" {
+			b, _ := io.ReadAll(r.Body)
+			rules = string(b)
+			w.Write([]byte(`{"status":"ok"}`))
+			return
+		}
+		w.Write([]byte(rules))
+	}))
+	defer ts.Close()
+
+	// the rules are at the root of the database, not of the client
+	client := new(F)
+	client.Init(ts.URL+"/app", "secret", nil)
+
+	b, err := client.Ref("users").GetRules()
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+
+	if string(b) != rules {
+		t.Fatalf("Expected the rules to be returned as is, got %s\n", b)
+	}
+
+	updated := "{\n  // everyone\n  \"rules\": {\".read\": true}\n}"
+
+	if err := client.SetRules([]byte(updated)); err != nil {
+		t.Fatalf("%v\n", err)
+	}
+
+	if rules != updated {
+		t.Fatalf("Expected the rules to be sent as is, got %s\n", rules)
+	}
+}
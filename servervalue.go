@@ -0,0 +1,102 @@
+package firebase
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ServerValue is a placeholder that Firebase replaces with a value computed
+// on the server when the data is written. It can be used anywhere in a value
+// passed to Set, Push or Update.
+type ServerValue struct {
+	sv interface{}
+}
+
+// ServerTimestamp is replaced by the server with the time of the write, in
+// milliseconds since the Unix epoch.
+var ServerTimestamp = ServerValue{sv: "timestamp"}
+
+// ServerIncrement returns a value that makes the server atomically add delta
+// to the current value, which is treated as zero when missing.
+func ServerIncrement(delta int64) ServerValue {
+	return ServerValue{sv: map[string]int64{"increment": delta}}
+}
+
+// Increment atomically adds delta to the number at the given path, which is
+// treated as zero when missing. The addition is performed by Firebase with
+// ServerIncrement, so concurrent increments are never lost.
+// An error matching ErrNotNumeric is returned if the current value is not a
+// number; this is checked with a separate read, so it cannot prevent a
+// concurrent write from replacing the number.
+func (f *F) Increment(path string, delta int64) error {
+	ref := f.derive(f.url(path))
+
+	parent := ref.Parent()
+	if parent == nil {
+		return fmt.Errorf("firebase: increment %s: cannot increment the root", ref.Url)
+	}
+
+	var v interface{}
+	if err := ref.Get("", Shallow().Params(), &v); err != nil {
+		return err
+	}
+
+	switch v.(type) {
+	case nil, float64, json.Number:
+	default:
+		return fmt.Errorf("firebase: increment %s: %w", ref.Url, ErrNotNumeric)
+	}
+
+	return parent.Update("", map[string]interface{}{ref.Key(): ServerIncrement(delta)}, nil)
+}
+
+// ServerTimeOffset returns the estimated difference between the clock of
+// Firebase and the local one, so that local times can be compared with
+// ServerTimestamp values. It is read from /.info/serverTimeOffset at the root
+// of the database, whatever the path given to Init, and is positive when the
+// local clock is late.
+func (f *F) ServerTimeOffset() (time.Duration, error) {
+	var ms *float64
+	if err := f.databaseRoot().Get(".info/serverTimeOffset", nil, &ms); err != nil {
+		return 0, err
+	}
+
+	if ms == nil {
+		return 0, errors.New("firebase: server time offset not available")
+	}
+
+	return time.Duration(*ms * float64(time.Millisecond)), nil
+}
+
+// ServerNow returns the current time according to the clock of Firebase,
+// which is the local time corrected by ServerTimeOffset.
+func (f *F) ServerNow() (time.Time, error) {
+	offset, err := f.ServerTimeOffset()
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return time.Now().Add(offset), nil
+}
+
+// MarshalJSON encodes the placeholder the way Firebase expects it.
+func (v ServerValue) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]interface{}{".sv": v.sv})
+}
+
+// Delete deletes the key it is written to, since Firebase removes the keys
+// set to null. It is mostly useful in the updates passed to UpdateChildren,
+// where it deletes some locations atomically along with the other changes,
+// whereas Remove deletes a single location with a call of its own.
+// Writing nil has the same effect; Delete makes the intent explicit.
+var Delete = deleteValue{}
+
+// deleteValue is the type of Delete.
+type deleteValue struct{}
+
+// MarshalJSON encodes Delete as null.
+func (deleteValue) MarshalJSON() ([]byte, error) {
+	return []byte("null"), nil
+}
@@ -0,0 +1,143 @@
+package firebase
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestServerValue(t *testing.T) {
+	v := struct {
+		Created ServerValue `json:"created"`
+		Count   ServerValue `json:"count"`
+	}{ServerTimestamp, ServerIncrement(2)}
+
+	b, err := json.Marshal(v)
+
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+
+	expected := `{"created":{".sv":"timestamp"},"count":{".sv":{"increment":2}}}`
+	if string(b) != expected {
+		t.Fatalf("Expected %s, got %s\n", expected, b)
+	}
+}
+
+func TestDelete(t *testing.T) {
+	data := map[string]interface{}{"a": "1", "b": "2"}
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "PATCH" {
+			var updates map[string]interface{}
+			json.NewDecoder(r.Body).Decode(&updates)
+
+			// like Firebase, null removes the key
+			for k, v := range updates {
+				if v == nil {
+					delete(data, k)
+				} else {
+					data[k] = v
+				}
+			}
+		}
+		json.NewEncoder(w).Encode(data)
+	}))
+	defer ts.Close()
+
+	client := new(F)
+	client.Init(ts.URL, "", nil)
+
+	err := client.UpdateChildren(map[string]interface{}{"a": Delete, "c": "3"}, nil)
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+
+	if _, ok := data["a"]; ok || data["b"] != "2" || data["c"] != "3" {
+		t.Fatalf("Expected a to be deleted, got %v\n", data)
+	}
+}
+
+func TestIncrement(t *testing.T) {
+	data := map[string]interface{}{"count": 1.0, "name": "x"}
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "PATCH" {
+			if r.URL.Path != "/stats.json" {
+				t.Errorf("Unexpected update of %s\n", r.URL.Path)
+			}
+
+			var updates map[string]map[string]map[string]float64
+			json.NewDecoder(r.Body).Decode(&updates)
+
+			for k, v := range updates {
+				n, _ := data[k].(float64)
+				data[k] = n + v[".sv"]["increment"]
+			}
+			w.Write([]byte(`{}`))
+			return
+		}
+
+		key := r.URL.Path[len("/stats/") : len(r.URL.Path)-len(".json")]
+		json.NewEncoder(w).Encode(data[key])
+	}))
+	defer ts.Close()
+
+	client := new(F)
+	client.Init(ts.URL, "", nil)
+
+	if err := client.Increment("stats/count", 2); err != nil {
+		t.Fatalf("%v\n", err)
+	}
+
+	if err := client.Increment("stats/views", 1); err != nil {
+		t.Fatalf("%v\n", err)
+	}
+
+	if data["count"] != 3.0 || data["views"] != 1.0 {
+		t.Fatalf("Unexpected counters %v\n", data)
+	}
+
+	if err := client.Increment("stats/name", 1); !errors.Is(err, ErrNotNumeric) {
+		t.Fatalf("Expected ErrNotNumeric, got %v\n", err)
+	}
+}
+
+func TestServerTimeOffset(t *testing.T) {
+	offset := "-1500"
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/.info/serverTimeOffset.json" {
+			t.Errorf("Unexpected path %s\n", r.URL.Path)
+		}
+		w.Write([]byte(offset))
+	}))
+	defer ts.Close()
+
+	client := new(F)
+	client.Init(ts.URL+"/app", "", nil)
+	client.StrictKeys = true
+
+	d, err := client.Ref("users").ServerTimeOffset()
+	if err != nil || d != -1500*time.Millisecond {
+		t.Fatalf("Unexpected offset %v and error %v\n", d, err)
+	}
+
+	now, err := client.ServerNow()
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+
+	if diff := time.Now().Add(-1500 * time.Millisecond).Sub(now); diff < 0 || diff > time.Second {
+		t.Fatalf("Expected the offset to be applied, got %v\n", now)
+	}
+
+	offset = "null"
+
+	if _, err := client.ServerTimeOffset(); err == nil {
+		t.Fatalf("Expected an error when the offset is not available\n")
+	}
+}
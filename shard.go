@@ -0,0 +1,59 @@
+package firebase
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+)
+
+// ShardedClient routes calls to one of several database instances, or
+// shards, depending on a key, for projects that split their data across
+// databases. Each shard is a configured F, with its own credentials.
+//
+//	users, err := firebase.NewShardedClient([]*firebase.F{db1, db2}, nil)
+//	...
+//	users.Shard(uid).Child("users/"+uid, nil, &user)
+type ShardedClient struct {
+	shards   []*F
+	shardFor func(key string, n int) int
+}
+
+// NewShardedClient returns a client routing keys to the given shards with
+// shardFor, which must return the index of the shard of key among n. When
+// nil, HashShard is used. Since keys move when shards are added, the order
+// and number of shards must stay the same over time.
+func NewShardedClient(shards []*F, shardFor func(key string, n int) int) (*ShardedClient, error) {
+	if len(shards) == 0 {
+		return nil, errors.New("firebase: a sharded client needs at least one shard")
+	}
+
+	if shardFor == nil {
+		shardFor = HashShard
+	}
+
+	return &ShardedClient{shards: append([]*F(nil), shards...), shardFor: shardFor}, nil
+}
+
+// HashShard spreads keys evenly over n shards using their FNV-1a hash.
+func HashShard(key string, n int) int {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+
+	return int(h.Sum32() % uint32(n))
+}
+
+// Shard returns the shard holding the data of the given key. It panics if
+// the shard function returns an index out of range.
+func (s *ShardedClient) Shard(key string) *F {
+	i := s.shardFor(key, len(s.shards))
+	if i < 0 || i >= len(s.shards) {
+		panic(fmt.Sprintf("firebase: shard %d of key %q out of range [0, %d)", i, key, len(s.shards)))
+	}
+
+	return s.shards[i]
+}
+
+// Shards returns all the shards, e.g. to query each of them.
+func (s *ShardedClient) Shards() []*F {
+	return append([]*F(nil), s.shards...)
+}
@@ -0,0 +1,82 @@
+package firebase
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestShardedClient(t *testing.T) {
+	var shards []*F
+	var fakes []*Fake
+
+	for i := 0; i < 3; i++ {
+		fake := NewFake()
+
+		f := new(F)
+		f.Init("https://db"+strconv.Itoa(i)+".firebaseio.com", "token"+strconv.Itoa(i), fake)
+
+		shards = append(shards, f)
+		fakes = append(fakes, fake)
+	}
+
+	s, err := NewShardedClient(shards, nil)
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+
+	counts := make([]int, len(shards))
+
+	for i := 0; i < 300; i++ {
+		key := "user" + strconv.Itoa(i)
+
+		shard := s.Shard(key)
+		if shard != s.Shard(key) {
+			t.Fatalf("Expected %s to always be routed to the same shard\n", key)
+		}
+
+		if _, err := shard.Set("users/"+key, i, nil); err != nil {
+			t.Fatalf("%v\n", err)
+		}
+
+		counts[HashShard(key, len(shards))]++
+	}
+
+	for i, fake := range fakes {
+		users, _ := fake.Data("users").(map[string]interface{})
+		if len(users) != counts[i] || counts[i] < 50 {
+			t.Fatalf("Unexpected distribution %v, shard %d holds %d users\n", counts, i, len(users))
+		}
+	}
+
+	if len(s.Shards()) != len(shards) {
+		t.Fatalf("Expected %d shards\n", len(shards))
+	}
+}
+
+func TestShardedClientCustom(t *testing.T) {
+	a, b := new(F), new(F)
+
+	s, _ := NewShardedClient([]*F{a, b}, func(key string, n int) int {
+		if key < "m" {
+			return 0
+		}
+		return 1
+	})
+
+	if s.Shard("alice") != a || s.Shard("zoe") != b {
+		t.Fatalf("Expected the shard function to be used\n")
+	}
+
+	if _, err := NewShardedClient(nil, nil); err == nil {
+		t.Fatalf("Expected an error without shards\n")
+	}
+
+	bad, _ := NewShardedClient([]*F{a}, func(string, int) int { return 1 })
+
+	defer func() {
+		if recover() == nil {
+			t.Fatalf("Expected a panic for an out of range shard\n")
+		}
+	}()
+	bad.Shard("a")
+}
@@ -0,0 +1,351 @@
+package firebase
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// streamRetry is the backoff used to reconnect streams when Retry is not set.
+var streamRetry = &RetryPolicy{BaseDelay: time.Second, MaxDelay: 30 * time.Second}
+
+// Event is a change notification received while watching a location.
+type Event struct {
+	// Type is the kind of event: "put", "patch", "resync" after a
+	// reconnection, or "cancel", "auth_revoked" and "error" when the stream
+	// is terminated.
+	Type string
+
+	// Path is the location of the change, relative to the watched location.
+	Path string
+
+	// Data is the new value at Path for put events, or the children to merge
+	// into the value at Path for patch events.
+	// The whole value at the watched location for resync events, which
+	// should be used to rebuild any local state.
+	Data interface{}
+
+	// Err is the reason the stream was terminated for error events.
+	Err error
+}
+
+// Watch streams the changes made to the data at the given path.
+// The first event is a put of the whole current value at the root path "/".
+// The returned function stops watching, after which the channel is closed.
+//
+// When the stream drops, Watch reconnects with the backoff delays of Retry,
+// or with default ones if it is not set, and sends a resync event with the
+// current value. An error event is sent before closing the channel when
+// reconnecting fails permanently, e.g. on 401 Unauthorized.
+//
+// Streaming is only supported by the built-in client; the request is not
+// bounded by Timeout, but a Timeout set on HTTPClient would interrupt it.
+func (f *F) Watch(path string, params map[string]string) (<-chan Event, func(), error) {
+	return f.WatchContext(context.Background(), path, params)
+}
+
+// WatchContext is like Watch but also stops watching when ctx is done.
+func (f *F) WatchContext(ctx context.Context, path string, params map[string]string) (<-chan Event, func(), error) {
+	c, ok := f.getApi().(*client)
+	if !ok {
+		return nil, nil, ErrUnsupported
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+
+	body, err := f.stream(ctx, c, path, params)
+	if err != nil {
+		cancel()
+		return nil, nil, err
+	}
+
+	events := make(chan Event)
+	id := f.watchers.add(cancel)
+
+	go func() {
+		defer close(events)
+		defer f.watchers.remove(id)
+		f.watch(ctx, c, path, params, body, events)
+	}()
+
+	return events, cancel, nil
+}
+
+// watchers tracks the streams opened by Watch so that Close can stop them.
+// A nil *watchers tracks nothing, for clients not initialized with Init.
+type watchers struct {
+	mu      sync.Mutex
+	next    int
+	cancels map[int]context.CancelFunc
+}
+
+// add tracks a stream stopped by cancel and returns its id.
+func (w *watchers) add(cancel context.CancelFunc) int {
+	if w == nil {
+		return 0
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.cancels == nil {
+		w.cancels = map[int]context.CancelFunc{}
+	}
+
+	w.next++
+	w.cancels[w.next] = cancel
+
+	return w.next
+}
+
+// remove stops tracking the stream with the given id once it ended.
+func (w *watchers) remove(id int) {
+	if w == nil {
+		return
+	}
+
+	w.mu.Lock()
+	delete(w.cancels, id)
+	w.mu.Unlock()
+}
+
+// stop stops all the tracked streams.
+func (w *watchers) stop() {
+	if w == nil {
+		return
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for id, cancel := range w.cancels {
+		cancel()
+		delete(w.cancels, id)
+	}
+}
+
+// watch sends the events read from body, reconnecting when the stream drops
+// until ctx is done or the stream is terminated.
+func (f *F) watch(ctx context.Context, c *client, path string, params map[string]string, body io.ReadCloser, events chan<- Event) {
+	p := f.Retry
+	if p == nil {
+		p = streamRetry
+	}
+
+	for {
+		err := readEvents(ctx, body, events)
+		body.Close()
+
+		// a nil error means Firebase terminated the stream on purpose
+		if err == nil || ctx.Err() != nil {
+			return
+		}
+
+		logf(f.Logger, "Firebase stream interrupted: %v\n", err)
+
+		body, err = f.reconnect(ctx, c, p, path, params, events)
+		if err != nil {
+			if ctx.Err() == nil {
+				sendEvent(ctx, events, Event{Type: "error", Err: err})
+			}
+			return
+		}
+	}
+}
+
+// reconnect opens the stream again, retrying transient failures, and sends
+// a resync event with the current value once connected.
+func (f *F) reconnect(ctx context.Context, c *client, p *RetryPolicy, path string, params map[string]string, events chan<- Event) (io.ReadCloser, error) {
+	for i := 0; ; i++ {
+		t := time.NewTimer(p.delay(i))
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return nil, ctx.Err()
+		case <-t.C:
+		}
+
+		body, err := f.stream(ctx, c, path, params)
+		if err == nil {
+			// read the value only after connecting so no change is missed
+			var ret *F
+			ret, err = f.child(ctx, path, params, nil)
+			if err == nil {
+				if !sendEvent(ctx, events, Event{Type: "resync", Path: "/", Data: ret.value}) {
+					body.Close()
+					return nil, ctx.Err()
+				}
+				return body, nil
+			}
+			body.Close()
+		}
+
+		var apiErr *APIError
+		if errors.As(err, &apiErr) && apiErr.StatusCode < 500 {
+			return nil, err
+		}
+
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		logf(f.Logger, "Cannot reconnect Firebase stream: %v\n", err)
+	}
+}
+
+// sendEvent sends e unless ctx is done first, and reports whether it did.
+func sendEvent(ctx context.Context, events chan<- Event, e Event) bool {
+	select {
+	case events <- e:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// stream opens an event stream on the given path with the credentials of f.
+func (f *F) stream(ctx context.Context, c *client, path string, params map[string]string) (io.ReadCloser, error) {
+	if err := f.checkPath(f.url(path)); err != nil {
+		return nil, err
+	}
+
+	auth, err := f.credential()
+	if err != nil {
+		return nil, err
+	}
+
+	return c.stream(ctx, f.url(path), auth, f.withParams(params))
+}
+
+// stream opens an event stream on the given Firebase URL.
+func (c *client) stream(ctx context.Context, path, auth string, params map[string]string) (io.ReadCloser, error) {
+	auth, header := c.bearer(auth, nil)
+	path = c.url(path, auth, params)
+
+	if err := c.wait(ctx); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", path, nil)
+	if err != nil {
+		return nil, redactError(err)
+	}
+
+	c.setHeader(req, header)
+	req.Header.Set("Accept", "text/event-stream")
+	logf(c.logger, "Streaming %q\n", redact(path))
+
+	res, err := c.getHTTPClient().Do(req)
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
+
+		return nil, redactError(err)
+	}
+
+	if res.StatusCode >= 400 {
+		defer res.Body.Close()
+
+		ret, _ := ioutil.ReadAll(res.Body)
+		return nil, newAPIError(res.StatusCode, scrub(string(ret), secrets(req.URL)...))
+	}
+
+	return res.Body, nil
+}
+
+// readEvents parses the server-sent events read from r and sends them on
+// events until the stream ends, Firebase terminates it or ctx is done.
+func readEvents(ctx context.Context, r io.Reader, events chan<- Event) error {
+	br := bufio.NewReader(r)
+
+	var typ, data string
+
+	for {
+		line, err := br.ReadString('\n')
+		if err != nil {
+			return err
+		}
+
+		line = strings.TrimRight(line, "\r\n")
+
+		switch {
+		case line == "":
+			// a blank line dispatches the event read so far
+			if typ == "" {
+				continue
+			}
+
+			e, done, err := parseEvent(typ, data)
+			typ, data = "", ""
+
+			if err != nil {
+				return err
+			}
+
+			if e != nil && !sendEvent(ctx, events, *e) {
+				return ctx.Err()
+			}
+
+			if done {
+				return nil
+			}
+
+		case strings.HasPrefix(line, ":"):
+			// comments are only used to keep the connection alive
+
+		default:
+			field, value := line, ""
+			if i := strings.Index(line, ":"); i >= 0 {
+				field, value = line[:i], strings.TrimPrefix(line[i+1:], " ")
+			}
+
+			switch field {
+			case "event":
+				typ = value
+			case "data":
+				if data != "" {
+					data += "\n"
+				}
+				data += value
+			}
+		}
+	}
+}
+
+// parseEvent decodes an event received from Firebase. It returns a nil event
+// for keep-alives and reports whether Firebase is terminating the stream.
+func parseEvent(typ, data string) (*Event, bool, error) {
+	switch typ {
+	case "keep-alive":
+		return nil, false, nil
+
+	case "put", "patch":
+		var payload struct {
+			Path string      `json:"path"`
+			Data interface{} `json:"data"`
+		}
+
+		err := json.Unmarshal([]byte(data), &payload)
+		if err != nil {
+			return nil, false, err
+		}
+
+		return &Event{Type: typ, Path: payload.Path, Data: payload.Data}, false, nil
+	}
+
+	// cancel and auth_revoked come with a reason and end the stream
+	var reason interface{}
+	if err := json.Unmarshal([]byte(data), &reason); err != nil {
+		reason = data
+	}
+
+	return &Event{Type: typ, Data: reason}, true, nil
+}
@@ -0,0 +1,170 @@
+package firebase
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWatch(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Accept") != "text/event-stream" {
+			t.Errorf("Expected an event stream request, got %q\n", r.Header.Get("Accept"))
+		}
+
+		w.Write([]byte("event: put\n" +
+			"data: {\"path\":\"/\",\"data\":{\"a\":1}}\n\n" +
+			": comment\n\n" +
+			"event: keep-alive\n" +
+			"data: null\n\n" +
+			"event: patch\r\n" +
+			"data: {\"path\":\"/b\",\"data\":{\"c\":2}}\r\n\r\n" +
+			"event: cancel\n" +
+			"data: \"permission denied\"\n\n"))
+	}))
+	defer ts.Close()
+
+	client := new(F)
+	client.Init(ts.URL, "", nil)
+
+	events, stop, err := client.Watch("users", nil)
+
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	defer stop()
+
+	var got []Event
+	for e := range events {
+		got = append(got, e)
+	}
+
+	if len(got) != 3 {
+		t.Fatalf("Expected 3 events, got %v\n", got)
+	}
+
+	if got[0].Type != "put" || got[0].Path != "/" || got[0].Data.(map[string]interface{})["a"] != float64(1) {
+		t.Fatalf("Unexpected put event %v\n", got[0])
+	}
+
+	if got[1].Type != "patch" || got[1].Path != "/b" {
+		t.Fatalf("Unexpected patch event %v\n", got[1])
+	}
+
+	if got[2].Type != "cancel" || got[2].Data != "permission denied" {
+		t.Fatalf("Unexpected cancel event %v\n", got[2])
+	}
+}
+
+func TestWatchReconnect(t *testing.T) {
+	var streams int
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Accept") != "text/event-stream" {
+			w.Write([]byte(`{"a":2}`))
+			return
+		}
+
+		streams++
+		switch streams {
+		case 1:
+			w.Write([]byte("event: put\ndata: {\"path\":\"/\",\"data\":{\"a\":1}}\n\n"))
+		case 2:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case 3:
+			w.Write([]byte("event: put\ndata: {\"path\":\"/\",\"data\":{\"a\":2}}\n\n"))
+		default:
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer ts.Close()
+
+	client := new(F)
+	client.Init(ts.URL, "", nil)
+	client.Retry = &RetryPolicy{BaseDelay: time.Millisecond}
+
+	events, stop, err := client.Watch("users", nil)
+
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	defer stop()
+
+	var types []string
+	var last Event
+	for e := range events {
+		types = append(types, e.Type)
+		last = e
+	}
+
+	if strings.Join(types, ",") != "put,resync,put,error" {
+		t.Fatalf("Unexpected events %v\n", types)
+	}
+
+	var apiErr *APIError
+	if !errors.As(last.Err, &apiErr) || apiErr.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("Expected a terminal 401 error, got %v\n", last.Err)
+	}
+}
+
+func TestWatchStop(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("event: put\ndata: {\"path\":\"/\",\"data\":null}\n\n"))
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer ts.Close()
+
+	client := new(F)
+	client.Init(ts.URL, "", nil)
+
+	events, stop, err := client.Watch("users", nil)
+
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+
+	<-events
+	stop()
+
+	for range events {
+	}
+}
+
+func TestClose(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("event: put\ndata: {\"path\":\"/\",\"data\":null}\n\n"))
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer ts.Close()
+
+	client := new(F)
+	client.Init(ts.URL, "", nil)
+
+	var streams []<-chan Event
+	for _, path := range []string{"users", "messages"} {
+		events, _, err := client.Ref(path).Watch("", nil)
+		if err != nil {
+			t.Fatalf("%v\n", err)
+		}
+		<-events
+		streams = append(streams, events)
+	}
+
+	if err := client.Close(); err != nil {
+		t.Fatalf("%v\n", err)
+	}
+
+	for _, events := range streams {
+		for range events {
+		}
+	}
+
+	if len(client.watchers.cancels) != 0 {
+		t.Fatalf("Expected no stream to be left, got %d\n", len(client.watchers.cancels))
+	}
+}
@@ -0,0 +1,19 @@
+package firebase
+
+import "net/http"
+
+// Tracer integrates the client with a distributed tracing system, such as
+// OpenTelemetry, without this package depending on it.
+//
+// An OpenTelemetry implementation would start a span from req.Context()
+// with the http.method and http.url attributes, inject the span context into
+// req.Header with the configured propagator, and set the
+// http.status_code attribute and record the error when the span is ended.
+type Tracer interface {
+	// StartSpan is called before req is sent, with its URL stripped of
+	// credentials. Headers added to req, such as traceparent, are sent with
+	// the request. The returned function is called once the response headers
+	// are received, with the status code, or zero if no response was
+	// received, and the error of the request, if any.
+	StartSpan(req *http.Request, url string) (end func(status int, err error))
+}
@@ -0,0 +1,53 @@
+package firebase
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type testTracer struct {
+	urls     []string
+	statuses []int
+}
+
+type spanKey struct{}
+
+func (t *testTracer) StartSpan(req *http.Request, url string) func(int, error) {
+	t.urls = append(t.urls, url)
+	if parent, ok := req.Context().Value(spanKey{}).(string); ok {
+		req.Header.Set("Traceparent", parent)
+	}
+
+	return func(status int, err error) {
+		t.statuses = append(t.statuses, status)
+	}
+}
+
+func TestTracer(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Traceparent") != "parent" {
+			t.Errorf("Expected the trace context to be propagated\n")
+		}
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer ts.Close()
+
+	tracer := new(testTracer)
+
+	client := new(F)
+	client.Init(ts.URL, "secret", nil)
+	client.Tracer = tracer
+
+	ctx := context.WithValue(context.Background(), spanKey{}, "parent")
+	client.ChildContext(ctx, "users", nil, nil)
+
+	if len(tracer.urls) != 1 || tracer.urls[0] != ts.URL+"/users.json?auth="+redacted {
+		t.Fatalf("Unexpected spans %q\n", tracer.urls)
+	}
+
+	if len(tracer.statuses) != 1 || tracer.statuses[0] != http.StatusUnauthorized {
+		t.Fatalf("Expected the span to end with 401, got %v\n", tracer.statuses)
+	}
+}
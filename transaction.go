@@ -0,0 +1,40 @@
+package firebase
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrAbortTransaction can be returned by a transaction function to stop the
+// transaction without writing anything. Transaction then returns it as is.
+var ErrAbortTransaction = errors.New("firebase: transaction aborted")
+
+// MaxTransactionRetries is the number of times Transaction retries the write
+// when the data changed concurrently.
+const MaxTransactionRetries = 25
+
+// Transaction atomically updates the value at the given path.
+// It reads the current value, calls fn to compute the new one and writes it
+// only if the data has not changed in the meantime. When it has, the whole
+// read-modify-write cycle is retried, so fn may be called several times and
+// should not have side effects.
+func (f *F) Transaction(path string, fn func(current interface{}) (interface{}, error)) error {
+	for i := 0; i <= MaxTransactionRetries; i++ {
+		current, etag, err := f.GetWithETag(path)
+		if err != nil {
+			return err
+		}
+
+		value, err := fn(current)
+		if err != nil {
+			return err
+		}
+
+		_, err = f.SetIfMatch(path, value, etag)
+		if !errors.Is(err, ErrETagMismatch) {
+			return err
+		}
+	}
+
+	return fmt.Errorf("firebase: transaction on %s/%s: too many retries: %w", f.Url, path, ErrETagMismatch)
+}
@@ -0,0 +1,43 @@
+package firebase
+
+import (
+	"testing"
+)
+
+func TestTransaction(t *testing.T) {
+	ts := etagServer(t, `1`)
+	defer ts.Close()
+
+	client := new(F)
+	client.Init(ts.URL, "", nil)
+
+	calls := 0
+	err := client.Transaction("counter", func(current interface{}) (interface{}, error) {
+		calls++
+		return current.(float64) + 1, nil
+	})
+
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+
+	if calls != 1 {
+		t.Fatalf("Expected 1 call without contention, got %d\n", calls)
+	}
+
+	if v, _, _ := client.GetWithETag("counter"); v != float64(2) {
+		t.Fatalf("Expected the counter to be 2, got %v\n", v)
+	}
+
+	err = client.Transaction("counter", func(current interface{}) (interface{}, error) {
+		return nil, ErrAbortTransaction
+	})
+
+	if err != ErrAbortTransaction {
+		t.Fatalf("Expected ErrAbortTransaction, got %v\n", err)
+	}
+
+	if v, _, _ := client.GetWithETag("counter"); v != float64(2) {
+		t.Fatalf("Expected the aborted transaction not to write, got %v\n", v)
+	}
+}
@@ -0,0 +1,208 @@
+package firebase
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// maxKeyBytes is the longest key Firebase accepts, in UTF-8 bytes.
+const maxKeyBytes = 768
+
+// illegalKeyChars are the characters Firebase rejects in keys.
+const illegalKeyChars = ".#$[]/"
+
+// Validate checks that value can be written to Firebase: it must marshal to
+// JSON, which rules out NaN and infinite floats, and its keys must be
+// non-empty, at most 768 bytes long and free of control characters and of
+// '.', '#', '$', '[', ']' and '/'. The returned error describes the first
+// problem found and wraps ErrInvalidKey for invalid keys. The special keys
+// used for server values and priorities, like ".sv", are allowed.
+func Validate(value interface{}) error {
+	_, err := validate(value, false)
+	return err
+}
+
+// validate marshals value and checks its keys. With paths, the top-level
+// keys are paths whose segments are checked, as written by Update.
+func validate(value interface{}, paths bool) ([]byte, error) {
+	body, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := validateJSON(body, paths); err != nil {
+		return nil, err
+	}
+
+	return body, nil
+}
+
+// validateJSON checks the keys of the JSON encoded in body, like validate.
+func validateJSON(body []byte, paths bool) error {
+	var v interface{}
+	if err := json.Unmarshal(body, &v); err != nil {
+		return err
+	}
+
+	m, ok := v.(map[string]interface{})
+	if !paths || !ok {
+		return validateKeys(v, "")
+	}
+
+	for p, child := range m {
+		for _, k := range strings.Split(strings.Trim(p, "/"), "/") {
+			if err := validateKey(k, p); err != nil {
+				return err
+			}
+		}
+
+		if err := validateKeys(child, strings.Trim(p, "/")); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// validateKeys checks the keys of v, found at the given path.
+func validateKeys(v interface{}, path string) error {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, child := range v {
+			p := k
+			if path != "" {
+				p = path + "/" + k
+			}
+
+			if err := validateKey(k, p); err != nil {
+				return err
+			}
+
+			if err := validateKeys(child, p); err != nil {
+				return err
+			}
+		}
+
+	case []interface{}:
+		for i, child := range v {
+			if err := validateKeys(child, fmt.Sprintf("%s/%d", path, i)); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// validateKey checks a single key, found at the given path.
+func validateKey(k, path string) error {
+	switch k {
+	case ".sv", ".priority", ".value":
+		return nil
+	case "":
+		return fmt.Errorf("%w at %q: keys cannot be empty", ErrInvalidKey, path)
+	}
+
+	if len(k) > maxKeyBytes {
+		return fmt.Errorf("%w at %q: keys cannot be longer than %d bytes", ErrInvalidKey, path, maxKeyBytes)
+	}
+
+	if i := strings.IndexAny(k, illegalKeyChars); i >= 0 {
+		return fmt.Errorf("%w %q at %q: keys cannot contain %q", ErrInvalidKey, k, path, k[i])
+	}
+
+	if i := strings.IndexFunc(k, func(r rune) bool { return r < 0x20 || r == 0x7f }); i >= 0 {
+		return fmt.Errorf("%w %q at %q: keys cannot contain control characters", ErrInvalidKey, k, path)
+	}
+
+	return nil
+}
+
+// checkPath checks the keys of the given Firebase URL, relative to the root
+// of the database, when StrictKeys is set. The .json suffix of REST
+// endpoints and the special .settings and .info locations are allowed.
+func (f *F) checkPath(u string) error {
+	if !f.StrictKeys {
+		return nil
+	}
+
+	p := u
+	if pu, err := url.Parse(u); err == nil {
+		p = pu.EscapedPath()
+	}
+	if ru, err := url.Parse(f.root); err == nil {
+		p = strings.TrimPrefix(p, ru.EscapedPath())
+	}
+	p = strings.Trim(strings.TrimSuffix(p, suffix), "/")
+
+	path := p
+	if up, err := url.PathUnescape(p); err == nil {
+		path = up
+	}
+
+	for i, k := range strings.Split(p, "/") {
+		if k == "" || i == 0 && (k == ".settings" || k == ".info") {
+			continue
+		}
+
+		if uk, err := url.PathUnescape(k); err == nil {
+			k = uk
+		}
+
+		if err := validateKey(k, path); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// marshal encodes value to be written at the Firebase URL u, validating it
+// first with StrictKeys. With paths, the top-level keys of value are paths,
+// as written by Update. A json.RawMessage is written verbatim, since
+// json.Marshal would compact it.
+func (f *F) marshal(u string, value interface{}, paths bool) ([]byte, error) {
+	var body []byte
+	var err error
+
+	switch raw := value.(type) {
+	case json.RawMessage:
+		if len(raw) > 0 {
+			return raw, f.checkRaw(u, raw, paths)
+		}
+	case *json.RawMessage:
+		if raw != nil && len(*raw) > 0 {
+			return *raw, f.checkRaw(u, *raw, paths)
+		}
+	}
+
+	if f.StrictKeys {
+		body, err = validate(value, paths)
+	} else {
+		body, err = json.Marshal(value)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("firebase: marshal value for %s: %w", u, err)
+	}
+
+	return body, nil
+}
+
+// checkRaw checks that data, to be written as is at the Firebase URL u, is
+// valid JSON, and validates its keys with StrictKeys like marshal.
+func (f *F) checkRaw(u string, data []byte, paths bool) error {
+	if !json.Valid(data) {
+		return fmt.Errorf("firebase: invalid JSON for %s", u)
+	}
+
+	if f.StrictKeys {
+		if err := validateJSON(data, paths); err != nil {
+			return fmt.Errorf("firebase: invalid value for %s: %w", u, err)
+		}
+	}
+
+	return nil
+}
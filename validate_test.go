@@ -0,0 +1,119 @@
+package firebase
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	valid := []interface{}{
+		nil,
+		"a.b",
+		map[string]interface{}{"name": "Jack", "tags": []string{"a"}},
+		map[string]interface{}{"time": ServerTimestamp},
+		map[string]interface{}{".priority": 1, ".value": "x"},
+	}
+
+	for _, v := range valid {
+		if err := Validate(v); err != nil {
+			t.Errorf("Expected %v to be valid, got %v\n", v, err)
+		}
+	}
+
+	invalid := []interface{}{
+		map[string]interface{}{"": 1},
+		map[string]interface{}{"a\nb": 1},
+		map[string]interface{}{strings.Repeat("a", 769): 1},
+		map[string]interface{}{"users": []interface{}{map[string]int{"a/b": 1}}},
+	}
+	for _, c := range illegalKeyChars {
+		invalid = append(invalid, map[string]interface{}{"users": map[string]int{"a" + string(c) + "b": 1}})
+	}
+
+	for _, v := range invalid {
+		if err := Validate(v); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("Expected %v to be invalid, got %v\n", v, err)
+		}
+	}
+
+	if err := Validate(map[string]float64{"a": math.NaN()}); err == nil {
+		t.Fatalf("Expected NaN to be invalid\n")
+	}
+
+	if err := Validate(math.Inf(1)); err == nil {
+		t.Fatalf("Expected infinity to be invalid\n")
+	}
+}
+
+func TestStrictKeys(t *testing.T) {
+	fake := NewFake()
+
+	client := new(F)
+	client.Init("https://example.firebaseio.com", "", fake)
+
+	if _, err := client.Set("users", map[string]int{"a.b": 1}, nil); err != nil {
+		t.Fatalf("Expected keys not to be checked by default, got %v\n", err)
+	}
+
+	client.StrictKeys = true
+
+	if _, err := client.Set("users", map[string]int{"a.b": 1}, nil); !errors.Is(err, ErrInvalidKey) || !strings.Contains(err.Error(), `"a.b"`) {
+		t.Fatalf("Expected a descriptive error, got %v\n", err)
+	}
+
+	if _, err := client.Ref("users").Push(map[string]int{"$a": 1}, nil); !errors.Is(err, ErrInvalidKey) {
+		t.Fatalf("Expected Push to validate keys, got %v\n", err)
+	}
+
+	// the keys of updates are paths
+	if err := client.Update("", map[string]int{"users/a/age": 1}, nil); err != nil {
+		t.Fatalf("%v\n", err)
+	}
+
+	if err := client.Update("", map[string]int{"users/a#/age": 1}, nil); !errors.Is(err, ErrInvalidKey) {
+		t.Fatalf("Expected Update to validate paths, got %v\n", err)
+	}
+}
+
+func TestStrictPaths(t *testing.T) {
+	client := new(F)
+	client.Init("https://example.firebaseio.com", "", NewFake())
+
+	if _, err := client.ChildE("users/a.b", nil, nil); err != nil {
+		t.Fatalf("Expected paths not to be checked by default, got %v\n", err)
+	}
+
+	client.StrictKeys = true
+
+	for _, c := range []string{".", "#", "$", "[", "]"} {
+		path := "users/a" + c + "b"
+
+		if _, err := client.ChildE(path, nil, nil); !errors.Is(err, ErrInvalidKey) || !strings.Contains(err.Error(), c) {
+			t.Errorf("Expected a descriptive error for %q, got %v\n", path, err)
+		}
+
+		if _, err := client.Set(path, 1, nil); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("Expected Set to check %q, got %v\n", path, err)
+		}
+
+		if err := client.Ref(path).Update("", map[string]int{"a": 1}, nil); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("Expected Update to check %q, got %v\n", path, err)
+		}
+	}
+
+	for _, path := range []string{"users/a", "/users//a/", "users/a.json", "users/a/.priority"} {
+		if _, err := client.ChildE(path, nil, nil); err != nil {
+			t.Errorf("Expected %q to be valid, got %v\n", path, err)
+		}
+	}
+
+	if _, err := client.Ref("users/a.b").ChildE("", nil, nil); !errors.Is(err, ErrInvalidKey) {
+		t.Fatalf("Expected the path of the reference to be checked, got %v\n", err)
+	}
+
+	if err := client.checkPath(client.Root().url(".settings/rules")); err != nil {
+		t.Fatalf("Expected the rules location to be valid, got %v\n", err)
+	}
+}